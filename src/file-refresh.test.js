@@ -0,0 +1,93 @@
+import { describe, it } from 'node:test';
+import assert from 'node:assert/strict';
+import { compileExcludePatterns } from './watcher/exclude-patterns.js';
+import { freshnessMtimes, refreshEntries, watcherPath } from './service/file-refresh.js';
+
+const config = {
+  projects: [
+    { name: 'Game', language: 'cpp', paths: ['D:/Proj/Source'] },
+    { name: 'Rules', language: 'csharp', paths: ['D:/Proj/Source'], extensions: ['.cs'], includePatterns: ['*.Build.cs', '*.Target.cs'] },
+    { name: 'Tools', language: 'cpp', paths: ['/home/dev/tools'] }
+  ],
+  exclude: ['Intermediate', '**/ThirdParty/**']
+};
+const excludes = compileExcludePatterns(config.exclude);
+const noRows = () => undefined;
+
+describe('watcherPath', () => {
+  it('joins the base path and relative path as the watcher spells them', () => {
+    const target = { project: config.projects[0], basePath: 'D:/Proj/Source' };
+    assert.equal(watcherPath(target, '/mnt/d/proj/Source/Aim/Aim.h'), 'D:\\Proj\\Source\\Aim\\Aim.h');
+    assert.equal(watcherPath(target, 'd:\\Proj\\Source\\Aim.h'), 'D:\\Proj\\Source\\Aim.h');
+    assert.equal(watcherPath({ basePath: '/home/dev/tools' }, '/home/dev/tools/x/B.h'), '/home/dev/tools/x/B.h');
+  });
+});
+
+describe('refreshEntries', () => {
+  it('parses a file of a matching project under the watcher spelling', () => {
+    const { entries, skipped } = refreshEntries(config, [
+      { path: '/mnt/d/proj/Source/Aim/AimComponent.h', mtime: 1700000000000.5, content: 'class UAimComponent : public UActorComponent\n{\n};\n' }
+    ], excludes, noRows);
+    assert.deepEqual(skipped, []);
+    assert.equal(entries.length, 1);
+    const [entry] = entries;
+    assert.equal(entry.path, 'D:\\Proj\\Source\\Aim\\AimComponent.h');
+    assert.equal(entry.project, 'Game');
+    assert.equal(entry.module, 'Game.Aim');
+    assert.equal(entry.relativePath, 'Aim/AimComponent.h');
+    assert.equal(entry.mtime, 1700000000000);
+    assert.ok(entry.types.some(t => t.name === 'UAimComponent'));
+  });
+
+  it('keeps the spelling of a file already stored', () => {
+    const stored = { 'd:/proj/source/aim.h': { path: 'd:\\proj\\source\\Aim.h' } };
+    const { entries } = refreshEntries(config, [{ path: '/mnt/d/proj/Source/Aim.h', mtime: 1, content: '' }], excludes, key => stored[key]);
+    assert.equal(entries[0].path, 'd:\\proj\\source\\Aim.h');
+  });
+
+  it('skips files no project indexes', () => {
+    const files = [
+      { path: 'D:/Elsewhere/Aim.h', mtime: 1, content: '' },
+      { path: 'D:/Proj/Source/Notes.txt', mtime: 1, content: '' },
+      // Rules only collects its includePatterns
+      { path: 'D:/Proj/Source/Tool/Program.cs', mtime: 1, content: '' }
+    ];
+    const { entries, skipped } = refreshEntries(config, files, excludes, noRows);
+    assert.deepEqual(entries, []);
+    assert.deepEqual(skipped, files.map(f => f.path));
+  });
+
+  it('skips excluded files', () => {
+    const files = [
+      { path: 'D:/Proj/Source/Intermediate/Build/Aim.generated.h', mtime: 1, content: '' },
+      { path: '/mnt/d/proj/Source/ThirdParty/Lib/Lib.h', mtime: 1, content: '' }
+    ];
+    const { entries, skipped } = refreshEntries(config, files, excludes, noRows);
+    assert.deepEqual(entries, []);
+    assert.equal(skipped.length, 2);
+  });
+
+  it('ingests files its includePatterns collect', () => {
+    const { entries } = refreshEntries(config, [{ path: 'D:/Proj/Source/Game.Build.cs', mtime: 1, content: 'public class Game : ModuleRules {}' }], excludes, noRows);
+    assert.equal(entries.length, 1);
+    assert.equal(entries[0].project, 'Rules');
+  });
+});
+
+describe('freshnessMtimes', () => {
+  it('answers indexed and not yet indexed files of the searched directory', () => {
+    // The watcher stored the config spelling; the proxy sends the mount one
+    const stored = { 'd:/proj/source/aim/aim.h': { mtime: 1700000000000 } };
+    const paths = [
+      '/mnt/d/proj/Source/Aim/Aim.h',
+      '/mnt/d/proj/Source/Aim/AimNew.cpp',
+      '/mnt/d/proj/Source/Aim/Aim.txt',
+      '/mnt/d/proj/Source/Aim/Intermediate/Aim.gen.h',
+      '/mnt/d/proj/Source/Aim/Tool.cs'
+    ];
+    assert.deepEqual(freshnessMtimes(config, paths, excludes, key => stored[key]), {
+      '/mnt/d/proj/Source/Aim/Aim.h': 1700000000000,
+      '/mnt/d/proj/Source/Aim/AimNew.cpp': null
+    });
+  });
+});
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// ── /batch lookups ───────────────────────────────────────────
+//
+// The service runs several index queries ({method, args}, the memory-index
+// method names) in one POST /batch. Lookups a hook needs from the same
+// workspace go together: the smart-routing candidates for a pattern, one
+// file-name search per literal, the members of every type in a file. Grep
+// isn't a batch method and keeps its own request.
+
+const maxBatchQueries = 10 // the service's per-request limit
+
+type batchQuery struct {
+	Method string        `json:"method"`
+	Args   []interface{} `json:"args"`
+}
+
+// batchLookup runs queries against the service at svcURL, ten per request,
+// and returns each query's raw result in order. A query the service
+// rejected has a nil result; ok is false when a request failed.
+func batchLookup(ctx context.Context, svcURL string, queries []batchQuery) (results []json.RawMessage, ok bool) {
+	results = make([]json.RawMessage, len(queries))
+	for start := 0; start < len(queries); start += maxBatchQueries {
+		chunk := queries[start:min(start+maxBatchQueries, len(queries))]
+		body, err := json.Marshal(map[string]interface{}{"queries": chunk})
+		if err != nil {
+			return results, false
+		}
+		var resp struct {
+			Results []struct {
+				Result json.RawMessage `json:"result"`
+				Error  string          `json:"error"`
+			} `json:"results"`
+		}
+		if !sendJSON(ctx, http.MethodPost, svcURL+"/batch", body, &resp) {
+			return results, false
+		}
+		for i, r := range resp.Results {
+			if i < len(chunk) && r.Error == "" {
+				results[start+i] = r.Result
+			}
+		}
+	}
+	return results, true
+}
+
+// batchResult decodes one query's result, or returns the zero T.
+func batchResult[T any](raw json.RawMessage) T {
+	var v T
+	if raw != nil {
+		json.Unmarshal(raw, &v)
+	}
+	return v
+}
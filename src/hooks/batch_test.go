@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeBatchService answers /batch with answer(method, name) for each query
+// and counts the requests.
+func fakeBatchService(t *testing.T, answer func(method, name string) interface{}) (*httptest.Server, *int) {
+	requests := 0
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/batch" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		var body struct {
+			Queries []struct {
+				Method string        `json:"method"`
+				Args   []interface{} `json:"args"`
+			} `json:"queries"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Queries) > maxBatchQueries {
+			http.Error(w, `{"error":"Maximum 10 queries per batch"}`, 400)
+			return
+		}
+		var results []map[string]interface{}
+		for _, q := range body.Queries {
+			results = append(results, map[string]interface{}{"result": answer(q.Method, fmt.Sprint(q.Args[0]))})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	t.Cleanup(svc.Close)
+	return svc, &requests
+}
+
+func TestSmartRoute(t *testing.T) {
+	defer func(p string) { breakerStatePath = p }(breakerStatePath)
+	breakerStatePath = filepath.Join(t.TempDir(), "breaker.json")
+
+	svc, requests := fakeBatchService(t, func(method, name string) interface{} {
+		if method == "findMember" && name == "TickAim" {
+			return []map[string]interface{}{{"name": "TickAim", "member_kind": "function", "type_name": "UAimComponent", "path": "Game/Aim.cpp", "line": 42}}
+		}
+		return []interface{}{}
+	})
+	targets := []workspaceRoute{{URL: svc.URL}}
+
+	// The type candidate finds nothing, so the member candidate answers
+	lookups := []symbolLookup{{"findTypeByName", "TickAim"}, {"findMember", "TickAim"}}
+	all := func(string) bool { return true }
+	got := smartRoute(context.Background(), targets, lookups, all)
+	if !strings.Contains(got, "Game/Aim.cpp:42: function UAimComponent::TickAim") {
+		t.Errorf("got %q", got)
+	}
+	if *requests != 1 {
+		t.Errorf("%d requests for two candidates, want 1", *requests)
+	}
+
+	// A workspace that doesn't answer is named, not dropped silently
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", 503)
+	}))
+	defer down.Close()
+	got = smartRoute(context.Background(), []workspaceRoute{{URL: svc.URL, Name: "game"}, {URL: down.URL, Name: "engine"}}, lookups, all)
+	if !strings.HasSuffix(got, "[unreal-index] workspace engine unavailable, results incomplete.") {
+		t.Errorf("with a failed workspace got %q", got)
+	}
+
+	// Hits outside the Grep's path or glob don't answer it
+	headers := func(file string) bool { return strings.HasSuffix(file, ".h") }
+	if got := smartRoute(context.Background(), targets, lookups, headers); got != "" {
+		t.Errorf("filtered smartRoute = %q, want none", got)
+	}
+}
+
+func TestBatchLookupSplitsAtLimit(t *testing.T) {
+	defer func(p string) { breakerStatePath = p }(breakerStatePath)
+	breakerStatePath = filepath.Join(t.TempDir(), "breaker.json")
+
+	svc, requests := fakeBatchService(t, func(method, name string) interface{} {
+		return []map[string]string{{"file": name + ".h"}}
+	})
+	var queries []batchQuery
+	for i := 0; i < 12; i++ {
+		queries = append(queries, batchQuery{Method: "findFileByName", Args: []interface{}{fmt.Sprintf("F%d", i), nil}})
+	}
+	results, ok := batchLookup(context.Background(), svc.URL, queries)
+	if !ok || *requests != 2 {
+		t.Fatalf("ok=%v after %d requests, want 2 requests", ok, *requests)
+	}
+	if got := batchResult[[]FindFileResult](results[11]); len(got) != 1 || got[0].File != "F11.h" {
+		t.Errorf("result 11 = %+v, want F11.h", got)
+	}
+}
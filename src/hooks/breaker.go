@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ── Circuit breaker for unavailable services ─────────────────
+//
+// When a workspace container is down every request waits out the timeout
+// before the hook falls back to the native tool, and a session makes dozens
+// of such calls. Each hook call is its own short-lived process, so failures
+// are counted per service in a state file shared through the temp
+// directory. After breakerThreshold failures in a row the service is skipped
+// outright for breakerCoolDown. After that one process claims the probe of
+// /health in the state file; the rest keep failing fast until the probe's
+// result is recorded.
+
+const (
+	breakerThreshold = 3
+	breakerCoolDown  = 30 * time.Second
+	breakerProbe     = time.Second
+	breakerLockWait  = 200 * time.Millisecond
+	breakerLockStale = 2 * time.Second // a lock this old was left by a killed process
+)
+
+type breakerEntry struct {
+	Failures   int   `json:"failures"`
+	OpenUntil  int64 `json:"openUntil,omitempty"`  // unix ms; set while the breaker is open
+	ProbeUntil int64 `json:"probeUntil,omitempty"` // unix ms; set while a process probes /health
+}
+
+// breakerStatePath is overridden by tests.
+var breakerStatePath = filepath.Join(os.TempDir(), "unreal-index-breaker.json")
+
+// breakerKey identifies the service behind a request URL.
+func breakerKey(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return u
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+func readBreakerState() map[string]breakerEntry {
+	state := map[string]breakerEntry{}
+	if data, err := os.ReadFile(breakerStatePath); err == nil {
+		json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+// updateBreakerState applies update to the state under the lock file. The
+// update is dropped if the lock can't be taken in time: the breaker only
+// saves time, so losing a count is harmless.
+func updateBreakerState(update func(map[string]breakerEntry)) {
+	lock := breakerStatePath + ".lock"
+	deadline := time.Now().Add(breakerLockWait)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return
+		}
+		if st, err := os.Stat(lock); err == nil && time.Since(st.ModTime()) > breakerLockStale {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	defer os.Remove(lock)
+
+	state := readBreakerState()
+	update(state)
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	tmp := breakerStatePath + ".tmp"
+	if os.WriteFile(tmp, data, 0o644) == nil {
+		os.Rename(tmp, breakerStatePath)
+	}
+}
+
+// breakerAllows reports whether a request to u should be made. Once the
+// cool-down has passed, the process that claims the probe asks /health:
+// success closes the breaker, failure keeps it open for another cool-down.
+// Other processes are refused until then. A claim left by a killed process
+// lapses after breakerProbe plus breakerLockStale.
+func breakerAllows(u string) bool {
+	key := breakerKey(u)
+	entry := readBreakerState()[key]
+	now := time.Now().UnixMilli()
+	if entry.OpenUntil == 0 {
+		return true
+	}
+	if now < entry.OpenUntil || now < entry.ProbeUntil {
+		return false
+	}
+
+	var closed, claimed bool
+	updateBreakerState(func(state map[string]breakerEntry) {
+		e := state[key]
+		switch {
+		case e.OpenUntil == 0:
+			closed = true
+		case now < e.OpenUntil || now < e.ProbeUntil:
+			// Another process claimed the probe first
+		default:
+			e.ProbeUntil = time.Now().Add(breakerProbe + breakerLockStale).UnixMilli()
+			state[key] = e
+			claimed = true
+		}
+	})
+	if closed || !claimed {
+		return closed
+	}
+
+	client := &http.Client{Timeout: breakerProbe}
+	resp, err := client.Get(key + "/health")
+	healthy := err == nil && resp.StatusCode == 200
+	if resp != nil {
+		resp.Body.Close()
+	}
+	updateBreakerState(func(state map[string]breakerEntry) {
+		if healthy {
+			delete(state, key)
+		} else {
+			state[key] = breakerEntry{Failures: state[key].Failures + 1, OpenUntil: time.Now().Add(breakerCoolDown).UnixMilli()}
+		}
+	})
+	return healthy
+}
+
+// breakerFailure counts a request that got no response, opening the breaker
+// at the threshold.
+func breakerFailure(u string) {
+	key := breakerKey(u)
+	updateBreakerState(func(state map[string]breakerEntry) {
+		entry := state[key]
+		entry.Failures++
+		if entry.Failures >= breakerThreshold {
+			entry.OpenUntil = time.Now().Add(breakerCoolDown).UnixMilli()
+		}
+		state[key] = entry
+	})
+}
+
+// breakerSuccess clears the failure count for u's service, if any.
+func breakerSuccess(u string) {
+	key := breakerKey(u)
+	if _, failing := readBreakerState()[key]; !failing {
+		return
+	}
+	updateBreakerState(func(state map[string]breakerEntry) { delete(state, key) })
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	defer func(p string) { breakerStatePath = p }(breakerStatePath)
+	breakerStatePath = filepath.Join(t.TempDir(), "breaker.json")
+
+	// A port nothing listens on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := "http://" + ln.Addr().String()
+	ln.Close()
+
+	var data struct{}
+	for i := 0; i < breakerThreshold; i++ {
+		if !breakerAllows(dead + "/grep") {
+			t.Fatalf("breaker open after %d failures", i)
+		}
+		fetchJSON(dead+"/grep?pattern=x", &data)
+	}
+	if breakerAllows(dead + "/find-file") {
+		t.Fatal("breaker still closed after reaching the threshold")
+	}
+
+	// Cool-down over, /health still down: stays open
+	expire := func(key string) {
+		updateBreakerState(func(state map[string]breakerEntry) {
+			e := state[key]
+			e.OpenUntil = time.Now().Add(-time.Second).UnixMilli()
+			state[key] = e
+		})
+	}
+	expire(dead)
+	if breakerAllows(dead + "/grep") {
+		t.Fatal("breaker closed although the /health probe failed")
+	}
+	if readBreakerState()[dead].OpenUntil <= time.Now().UnixMilli() {
+		t.Error("failed probe did not start another cool-down")
+	}
+
+	// Cool-down over, /health answers: closes
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer svc.Close()
+	updateBreakerState(func(state map[string]breakerEntry) {
+		state[svc.URL] = breakerEntry{Failures: breakerThreshold, OpenUntil: time.Now().Add(-time.Second).UnixMilli()}
+	})
+	if !breakerAllows(svc.URL + "/grep") {
+		t.Fatal("breaker open although the /health probe succeeded")
+	}
+	if _, ok := readBreakerState()[svc.URL]; ok {
+		t.Error("successful probe left the failure count")
+	}
+	if _, ok := readBreakerState()[dead]; !ok {
+		t.Error("closing one service's breaker cleared another's")
+	}
+}
+
+func TestBreakerSingleProbe(t *testing.T) {
+	defer func(p string) { breakerStatePath = p }(breakerStatePath)
+	breakerStatePath = filepath.Join(t.TempDir(), "breaker.json")
+
+	var probes atomic.Int32
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer svc.Close()
+	updateBreakerState(func(state map[string]breakerEntry) {
+		state[svc.URL] = breakerEntry{Failures: breakerThreshold, OpenUntil: time.Now().Add(-time.Second).UnixMilli()}
+	})
+
+	// Hook calls racing past the cool-down: one probes, the rest fail fast
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if breakerAllows(svc.URL + "/grep") {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := probes.Load(); n != 1 {
+		t.Errorf("%d /health probes, want 1", n)
+	}
+	if n := allowed.Load(); n != 1 {
+		t.Errorf("%d requests allowed during the probe, want only the prober's", n)
+	}
+	if !breakerAllows(svc.URL + "/grep") {
+		t.Error("breaker still open after the probe succeeded")
+	}
+
+	// A claim left by a process killed mid-probe lapses
+	updateBreakerState(func(state map[string]breakerEntry) {
+		state[svc.URL] = breakerEntry{
+			Failures:   breakerThreshold,
+			OpenUntil:  time.Now().Add(-time.Second).UnixMilli(),
+			ProbeUntil: time.Now().Add(-time.Millisecond).UnixMilli(),
+		}
+	})
+	if !breakerAllows(svc.URL+"/grep") || probes.Load() != 2 {
+		t.Error("lapsed probe claim not taken over")
+	}
+}
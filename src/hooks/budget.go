@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ── Latency budget ───────────────────────────────────────────
+//
+// Every tool call waits on the hook, so the whole call gets one deadline
+// (latencyBudgetMs in shared.hooks). Requests to the services run under
+// hookCtx and are cancelled when it expires; the hook then exits without a
+// decision and the native tool runs.
+
+const defaultLatencyBudget = 5 * time.Second
+
+// hookCtx is the context service requests run under. It carries the budget
+// deadline once main has started it.
+var hookCtx = context.Background()
+
+// exitMu is held while the hook writes its answer and exits.
+var exitMu sync.Mutex
+
+func latencyBudget() time.Duration {
+	if ms := hookConfig.LatencyBudgetMs; ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return defaultLatencyBudget
+}
+
+// startBudget starts the deadline for this hook call. When it passes, the
+// hook allows the tool, unless an answer is already being written. Requests
+// it cuts off are not charged to the services' circuit breakers: another
+// lookup may have used up the budget, so a slow answer says nothing about
+// the service. Transport errors and per-request timeouts still count.
+func startBudget() {
+	budget := latencyBudget()
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	hookCtx = ctx
+	time.AfterFunc(budget, func() {
+		cancel()
+		allow()
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestBudgetAllowsOnDeadline runs a hook call in a child process, since
+// allow exits: a request the service never answers ends in an allow with no
+// output once the budget passes, and the cut-off request isn't charged.
+func TestBudgetAllowsOnDeadline(t *testing.T) {
+	if u := os.Getenv("UNREAL_INDEX_HUNG_URL"); u != "" {
+		breakerStatePath = os.Getenv("UNREAL_INDEX_BREAKER")
+		hookConfig.LatencyBudgetMs = 100
+		startBudget()
+		var data struct{}
+		fetchJSON(u+"/grep?pattern=x", &data)
+		os.Exit(3) // the request returned before the deadline exited
+	}
+
+	hung := make(chan struct{})
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer svc.Close()
+	defer close(hung)
+	state := filepath.Join(t.TempDir(), "breaker.json")
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestBudgetAllowsOnDeadline$")
+	cmd.Env = append(os.Environ(), "UNREAL_INDEX_HUNG_URL="+svc.URL, "UNREAL_INDEX_BREAKER="+state)
+	start := time.Now()
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("hook call did not allow on the deadline: %v\n%s", err, out)
+	}
+	if len(out) > 0 {
+		t.Errorf("allow wrote %q, want no output", out)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("hook call took %v with a 100ms budget", elapsed)
+	}
+
+	defer func(p string) { breakerStatePath = p }(breakerStatePath)
+	breakerStatePath = state
+	if entry, ok := readBreakerState()[svc.URL]; ok {
+		t.Errorf("budget expiry charged the service: %+v", entry)
+	}
+}
+
+func TestBreakerAccounting(t *testing.T) {
+	defer func(p string) { breakerStatePath = p }(breakerStatePath)
+	breakerStatePath = filepath.Join(t.TempDir(), "breaker.json")
+
+	// A port nothing listens on: a transport error counts
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := "http://" + ln.Addr().String()
+	ln.Close()
+	var data struct{}
+	fetchJSON(dead+"/grep?pattern=x", &data)
+	if got := readBreakerState()[dead].Failures; got != 1 {
+		t.Errorf("transport error: %d failures, want 1", got)
+	}
+
+	// A request its context cut off does not
+	slow := make(chan struct{})
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-slow
+	}))
+	defer svc.Close()
+	defer close(slow)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if fetchJSONContext(ctx, svc.URL+"/grep?pattern=x", &data) {
+		t.Fatal("cut-off request reported success")
+	}
+	if entry, ok := readBreakerState()[svc.URL]; ok {
+		t.Errorf("cut-off request charged the service: %+v", entry)
+	}
+
+	// An answer clears the count
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer ok.Close()
+	updateBreakerState(func(state map[string]breakerEntry) { state[ok.URL] = breakerEntry{Failures: 2} })
+	if !fetchJSON(ok.URL+"/grep?pattern=x", &data) {
+		t.Fatal("request to a healthy service failed")
+	}
+	if entry, found := readBreakerState()[ok.URL]; found {
+		t.Errorf("answer left the failure count: %+v", entry)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ── Multi-workspace fan-out ──────────────────────────────────
+//
+// A search with no path, or one rooted above several workspaces (a repo root
+// holding both game and engine), goes to every matching workspace in
+// parallel. Ranked results are merged round-robin so each workspace's best
+// hits lead, duplicates are dropped, and hits are labeled by workspace.
+
+// searchTargets returns the workspaces a search rooted at path should query:
+// the one containing the path, every workspace below it, or all of them when
+// the path is unknown.
+func searchTargets(path string) []workspaceRoute {
+	if len(workspaceRoutes) == 0 {
+		return []workspaceRoute{{URL: resolveServiceURL(path)}}
+	}
+	if path == "" {
+		return workspaceRoutes
+	}
+	norm := normalizePath(path)
+	var below []workspaceRoute
+	for _, ws := range workspaceRoutes {
+		best := -1
+		for _, prefix := range ws.Prefixes {
+			best = max(best, routeScore(norm, prefix))
+		}
+		if best > 0 {
+			// Inside a workspace: it alone holds the path
+			return []workspaceRoute{routeFor(resolveServiceURL(path))}
+		}
+		if best == 0 {
+			below = append(below, ws)
+		}
+	}
+	if len(below) > 0 {
+		return below
+	}
+	return []workspaceRoute{routeFor(resolveServiceURL(path))}
+}
+
+// allTargets returns every workspace, the one serving path first. Symbol
+// lookups use it so a type defined in another workspace is still found.
+func allTargets(path string) []workspaceRoute {
+	first := routeFor(resolveServiceURL(path))
+	targets := []workspaceRoute{first}
+	for _, ws := range workspaceRoutes {
+		if ws.URL != first.URL {
+			targets = append(targets, ws)
+		}
+	}
+	return targets
+}
+
+// routeFor returns the configured workspace with the given URL.
+func routeFor(svcURL string) workspaceRoute {
+	for _, ws := range workspaceRoutes {
+		if ws.URL == svcURL {
+			return ws
+		}
+	}
+	return workspaceRoute{URL: svcURL}
+}
+
+// label names a workspace in merged output.
+func (ws workspaceRoute) label() string {
+	if ws.Name != "" {
+		return ws.Name
+	}
+	if ws.Port > 0 {
+		return fmt.Sprintf("port %d", ws.Port)
+	}
+	return ws.URL
+}
+
+// fanOut calls fetch for every target concurrently and returns the results in
+// target order.
+func fanOut[T any](targets []workspaceRoute, fetch func(workspaceRoute) T) []T {
+	out := make([]T, len(targets))
+	if len(targets) == 1 {
+		out[0] = fetch(targets[0])
+		return out
+	}
+	var wg sync.WaitGroup
+	for i, ws := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out[i] = fetch(ws)
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// interleave merges ranked lists round-robin, dropping items whose key was
+// already taken.
+func interleave[T any](lists [][]T, key func(T) string) []T {
+	var out []T
+	seen := map[string]bool{}
+	for i := 0; ; i++ {
+		more := false
+		for _, list := range lists {
+			if i >= len(list) {
+				continue
+			}
+			more = true
+			if k := key(list[i]); !seen[k] {
+				seen[k] = true
+				out = append(out, list[i])
+			}
+		}
+		if !more {
+			return out
+		}
+	}
+}
+
+// grepAll runs a /grep query against every target and merges the hits.
+// TotalMatches is summed, Truncated is set if any workspace truncated and
+// Unavailable names the workspaces that failed. ok is false only when every
+// workspace failed.
+func grepAll(targets []workspaceRoute, query url.Values) (data GrepResponse, ok bool) {
+	responses := fanOut(targets, func(ws workspaceRoute) *GrepResponse {
+		var resp GrepResponse
+		if !fetchJSON(ws.URL+"/grep?"+query.Encode(), &resp) || resp.Error != "" {
+			// Service down or Zoekt restarting: ask Zoekt directly
+			direct, found := zoektGrep(ws, query)
+			if !found {
+				return nil
+			}
+			resp = direct
+		}
+		for i := range resp.Results {
+			resp.Results[i].Workspace = ws.label()
+		}
+		return &resp
+	})
+	var lists [][]GrepResult
+	for i, resp := range responses {
+		if resp == nil {
+			data.Unavailable = append(data.Unavailable, targets[i].label())
+			continue
+		}
+		ok = true
+		lists = append(lists, resp.Results)
+		data.TotalMatches += resp.TotalMatches
+		data.Truncated = data.Truncated || resp.Truncated
+	}
+	data.Results = interleave(lists, func(r GrepResult) string { return fmt.Sprintf("%s:%d", r.File, r.Line) })
+	return data, ok
+}
+
+// unavailableNote tells that the named workspaces didn't answer a merged
+// search, or is empty when every workspace did.
+func unavailableNote(failed []string) string {
+	switch len(failed) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("workspace %s unavailable, results incomplete", failed[0])
+	}
+	return fmt.Sprintf("workspaces %s unavailable, results incomplete", strings.Join(failed, ", "))
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ── find(1) expressions ──────────────────────────────────────
+//
+// Parses a find argv (start paths, global options, expression) and evaluates
+// the expression against candidate paths from the index, the way find would
+// walk the tree: depth limits, -prune, -print and operator precedence
+// (! > -a > -o) included.
+
+// findNode is one node of a parsed find expression.
+type findNode struct {
+	Op   string // "and", "or", "not", or a primary such as "-name"
+	Kids []*findNode
+	Arg  string
+	Re   *regexp.Regexp
+}
+
+// findQuery is a parsed find command line.
+type findQuery struct {
+	Starts      []string  // start paths as typed ("." when omitted)
+	Expr        *findNode // nil matches everything
+	MaxDepth    int       // -1 when unlimited
+	MinDepth    int
+	HasAction   bool   // expression contains -print/-print0, so printing is explicit
+	Unsupported string // first primary we cannot evaluate from the index
+}
+
+// findCandidate is one path find would visit.
+type findCandidate struct {
+	Path  string // as find prints it (start-prefixed)
+	Dir   bool
+	Depth int // path segments below the start directory
+}
+
+type findParser struct {
+	args      []string
+	pos       int
+	q         *findQuery
+	regexType string
+}
+
+// parseFindArgs parses a find argv (args[0] is "find").
+func parseFindArgs(args []string) findQuery {
+	q := findQuery{MaxDepth: -1}
+	i := 1
+	for i < len(args) && (args[i] == "-H" || args[i] == "-L" || args[i] == "-P") {
+		i++
+	}
+	for i < len(args) && !isFindExprStart(args[i]) {
+		q.Starts = append(q.Starts, args[i])
+		i++
+	}
+	if len(q.Starts) == 0 {
+		q.Starts = []string{"."}
+	}
+	p := &findParser{args: args[i:], q: &q}
+	if len(p.args) > 0 {
+		q.Expr = p.parseOr()
+		if p.pos < len(p.args) && q.Unsupported == "" {
+			q.Unsupported = p.args[p.pos]
+		}
+	}
+	return q
+}
+
+func isFindExprStart(arg string) bool {
+	return strings.HasPrefix(arg, "-") && len(arg) > 1 || arg == "(" || arg == "!" || arg == ")" || arg == ","
+}
+
+func (p *findParser) peek() string {
+	if p.pos < len(p.args) {
+		return p.args[p.pos]
+	}
+	return ""
+}
+
+func (p *findParser) parseOr() *findNode {
+	left := p.parseAnd()
+	for p.peek() == "-o" || p.peek() == "-or" {
+		p.pos++
+		left = &findNode{Op: "or", Kids: []*findNode{left, p.parseAnd()}}
+	}
+	return left
+}
+
+func (p *findParser) parseAnd() *findNode {
+	left := p.parseUnary()
+	for {
+		switch p.peek() {
+		case "", "-o", "-or", ")", ",":
+			return left
+		case "-a", "-and":
+			p.pos++
+		}
+		left = &findNode{Op: "and", Kids: []*findNode{left, p.parseUnary()}}
+	}
+}
+
+func (p *findParser) parseUnary() *findNode {
+	switch p.peek() {
+	case "!", "-not":
+		p.pos++
+		return &findNode{Op: "not", Kids: []*findNode{p.parseUnary()}}
+	case "(":
+		p.pos++
+		n := p.parseOr()
+		if p.peek() == ")" {
+			p.pos++
+		} else {
+			p.fail("(")
+		}
+		return n
+	}
+	return p.parsePrimary()
+}
+
+func (p *findParser) fail(arg string) {
+	if p.q.Unsupported == "" {
+		p.q.Unsupported = arg
+	}
+}
+
+func (p *findParser) value() string {
+	if p.pos < len(p.args) {
+		v := p.args[p.pos]
+		p.pos++
+		return v
+	}
+	p.fail(p.args[p.pos-1])
+	return ""
+}
+
+func (p *findParser) parsePrimary() *findNode {
+	if p.pos >= len(p.args) {
+		p.fail("find")
+		return &findNode{Op: "-true"}
+	}
+	arg := p.args[p.pos]
+	p.pos++
+	switch arg {
+	case "-name", "-iname", "-path", "-ipath", "-wholename", "-iwholename", "-type":
+		return &findNode{Op: arg, Arg: p.value()}
+	case "-regex", "-iregex":
+		expr := p.value()
+		if p.regexType == "" || strings.HasPrefix(p.regexType, "emacs") || strings.Contains(p.regexType, "basic") {
+			expr = breToERE(expr)
+		}
+		if arg == "-iregex" {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			p.fail(arg)
+		}
+		return &findNode{Op: "-regex", Re: re}
+	case "-maxdepth", "-mindepth":
+		n, err := strconv.Atoi(p.value())
+		if err != nil {
+			p.fail(arg)
+		}
+		if arg == "-maxdepth" {
+			p.q.MaxDepth = n
+		} else {
+			p.q.MinDepth = n
+		}
+		return &findNode{Op: "-true"}
+	case "-regextype":
+		p.regexType = p.value()
+		return &findNode{Op: "-true"}
+	case "-print", "-print0":
+		p.q.HasAction = true
+		return &findNode{Op: "-print"}
+	case "-prune", "-true", "-false":
+		return &findNode{Op: arg}
+	case "-depth", "-d", "-xdev", "-mount", "-noleaf", "-follow", "-daystart", "-ignore_readdir_race",
+		"-noignore_readdir_race", "-nowarn", "-warn":
+		return &findNode{Op: "-true"}
+	}
+	// -exec/-delete have side effects; -size/-newer/-mtime/... need data the index lacks
+	p.fail(arg)
+	return &findNode{Op: "-false"}
+}
+
+// findEval holds per-candidate evaluation side effects.
+type findEval struct {
+	printed bool
+	pruned  bool
+}
+
+func (n *findNode) eval(c findCandidate, st *findEval) bool {
+	base := path.Base(c.Path)
+	switch n.Op {
+	case "and":
+		return n.Kids[0].eval(c, st) && n.Kids[1].eval(c, st)
+	case "or":
+		return n.Kids[0].eval(c, st) || n.Kids[1].eval(c, st)
+	case "not":
+		return !n.Kids[0].eval(c, st)
+	case "-name":
+		return fnmatch(n.Arg, base, false)
+	case "-iname":
+		return fnmatch(n.Arg, base, true)
+	case "-path", "-wholename":
+		return fnmatch(n.Arg, c.Path, false)
+	case "-ipath", "-iwholename":
+		return fnmatch(n.Arg, c.Path, true)
+	case "-regex":
+		return n.Re != nil && n.Re.MatchString(c.Path)
+	case "-type":
+		for _, t := range strings.Split(n.Arg, ",") {
+			if t == "d" && c.Dir || t == "f" && !c.Dir {
+				return true
+			}
+		}
+		return false
+	case "-print":
+		st.printed = true
+		return true
+	case "-prune":
+		if c.Dir {
+			st.pruned = true
+		}
+		return true
+	case "-false":
+		return false
+	}
+	return true
+}
+
+// Match reports whether find prints the candidate, and whether -prune stops
+// descent into it.
+func (q findQuery) Match(c findCandidate) (printed, pruned bool) {
+	if c.Depth < q.MinDepth || q.MaxDepth >= 0 && c.Depth > q.MaxDepth {
+		return false, false
+	}
+	if q.Expr == nil {
+		return true, false
+	}
+	var st findEval
+	ok := q.Expr.eval(c, &st)
+	if !q.HasAction {
+		st.printed = ok
+	}
+	return st.printed, st.pruned
+}
+
+// Run evaluates the query over the candidates of one start path and returns
+// the printed paths in traversal order. Ancestors are evaluated for -prune.
+func (q findQuery) Run(cands []findCandidate) []string {
+	sort.Slice(cands, func(i, j int) bool { return cands[i].Path < cands[j].Path })
+	pruned := map[string]bool{}
+	var out []string
+	for _, c := range cands {
+		if isFindPrunedBelow(c.Path, pruned) {
+			if c.Dir {
+				pruned[c.Path] = true
+			}
+			continue
+		}
+		printed, prune := q.Match(c)
+		if prune {
+			pruned[c.Path] = true
+		}
+		if printed {
+			out = append(out, c.Path)
+		}
+	}
+	return out
+}
+
+func isFindPrunedBelow(p string, pruned map[string]bool) bool {
+	// Walk printed ancestors textually; path.Dir would clean away a "./" start
+	for i := strings.LastIndex(p, "/"); i > 0; i = strings.LastIndex(p[:i], "/") {
+		if pruned[p[:i]] {
+			return true
+		}
+	}
+	return false
+}
+
+// NameLiterals returns /find-file search terms that every printed path must
+// contain, or ok=false when the expression is not constrained by name.
+func (n *findNode) NameLiterals() (lits []string, ok bool) {
+	if n == nil {
+		return nil, false
+	}
+	switch n.Op {
+	case "-name", "-iname":
+		if lit := globLiteral(n.Arg); len(lit) >= 3 {
+			return []string{lit}, true
+		}
+	case "-path", "-ipath", "-wholename", "-iwholename":
+		if lit := globLiteral(path.Base(n.Arg)); len(lit) >= 3 && !strings.Contains(path.Base(n.Arg), "*/") {
+			return []string{lit}, true
+		}
+	case "and":
+		if lits, ok := n.Kids[0].NameLiterals(); ok {
+			return lits, true
+		}
+		return n.Kids[1].NameLiterals()
+	case "or":
+		a, okA := n.Kids[0].NameLiterals()
+		b, okB := n.Kids[1].NameLiterals()
+		if okA && okB {
+			return append(a, b...), true
+		}
+	}
+	return nil, false
+}
+
+// FilesOnly reports whether the expression can only match regular files (-type f).
+func (n *findNode) FilesOnly() bool {
+	if n == nil {
+		return false
+	}
+	switch n.Op {
+	case "-type":
+		return n.Arg == "f"
+	case "and":
+		return n.Kids[0].FilesOnly() || n.Kids[1].FilesOnly()
+	case "or":
+		return n.Kids[0].FilesOnly() && n.Kids[1].FilesOnly()
+	}
+	return false
+}
+
+// NameExts returns the literal file extensions named by -name/-iname patterns.
+func (n *findNode) NameExts() []string {
+	if n == nil {
+		return nil
+	}
+	switch n.Op {
+	case "-name", "-iname":
+		ext := path.Ext(n.Arg)
+		if ext != "" && !strings.ContainsAny(ext, "*?[") {
+			return []string{strings.ToLower(ext)}
+		}
+	case "and", "or":
+		return append(n.Kids[0].NameExts(), n.Kids[1].NameExts()...)
+	}
+	return nil
+}
+
+// globLiteral returns the longest wildcard-free run of a filename pattern,
+// ignoring its extension (the service matches basenames without extension).
+func globLiteral(pattern string) string {
+	if ext := path.Ext(pattern); ext != "" && ext != pattern {
+		pattern = strings.TrimSuffix(pattern, ext)
+	}
+	best := ""
+	for _, part := range regexp.MustCompile(`[*?]|\[[^\]]*\]`).Split(pattern, -1) {
+		if len(part) > len(best) {
+			best = part
+		}
+	}
+	return strings.ReplaceAll(best, "\\", "")
+}
+
+// fnmatch implements fnmatch(3) without FNM_PATHNAME: * and ? also match '/'.
+// Supports [...] classes with ranges and ! or ^ negation, and backslash escapes.
+func fnmatch(pattern, s string, fold bool) bool {
+	if fold {
+		pattern, s = strings.ToLower(pattern), strings.ToLower(s)
+	}
+	return fnmatchAt(pattern, s)
+}
+
+func fnmatchAt(p, s string) bool {
+	for len(p) > 0 {
+		switch p[0] {
+		case '*':
+			for len(p) > 0 && p[0] == '*' {
+				p = p[1:]
+			}
+			if p == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if fnmatchAt(p, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+			p, s = p[1:], s[1:]
+		case '[':
+			if s == "" {
+				return false
+			}
+			matched, rest, ok := matchBracket(p, s[0])
+			if !ok {
+				// Unterminated class: treat [ literally
+				if s[0] != '[' {
+					return false
+				}
+				p, s = p[1:], s[1:]
+				continue
+			}
+			if !matched {
+				return false
+			}
+			p, s = rest, s[1:]
+		case '\\':
+			if len(p) > 1 {
+				p = p[1:]
+			}
+			fallthrough
+		default:
+			if s == "" || p[0] != s[0] {
+				return false
+			}
+			p, s = p[1:], s[1:]
+		}
+	}
+	return s == ""
+}
+
+// matchBracket matches c against the class at the start of p ("[...]").
+// Returns the remainder of the pattern after the class.
+func matchBracket(p string, c byte) (matched bool, rest string, ok bool) {
+	i := 1
+	negate := false
+	if i < len(p) && (p[i] == '!' || p[i] == '^') {
+		negate = true
+		i++
+	}
+	first := true
+	for i < len(p) && (p[i] != ']' || first) {
+		first = false
+		lo := p[i]
+		if lo == '\\' && i+1 < len(p) {
+			i++
+			lo = p[i]
+		}
+		hi := lo
+		if i+2 < len(p) && p[i+1] == '-' && p[i+2] != ']' {
+			hi = p[i+2]
+			i += 2
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+		i++
+	}
+	if i >= len(p) {
+		return false, "", false
+	}
+	return matched != negate, p[i+1:], true
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFnmatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		fold, want bool
+	}{
+		{"*.h", "Aim.h", false, true},
+		{"*.h", "Aim.cpp", false, false},
+		{"*/Private/*", "./Source/Private/A.cpp", false, true},
+		{"Aim?.h", "Aim2.h", false, true},
+		{"[A-C]*", "Bar.h", false, true},
+		{"[!A-C]*", "Bar.h", false, false},
+		{"AIM*", "aim.h", true, true},
+		{"AIM*", "aim.h", false, false},
+		{`\*.h`, "*.h", false, true},
+	}
+	for _, tt := range tests {
+		if got := fnmatch(tt.pattern, tt.s, tt.fold); got != tt.want {
+			t.Errorf("fnmatch(%q, %q, %v) = %v, want %v", tt.pattern, tt.s, tt.fold, got, tt.want)
+		}
+	}
+}
+
+func TestFindQueryRun(t *testing.T) {
+	cands := []findCandidate{
+		{Path: ".", Dir: true},
+		{Path: "./Source", Dir: true, Depth: 1},
+		{Path: "./Source/Aim.h", Depth: 2},
+		{Path: "./Source/Private", Dir: true, Depth: 2},
+		{Path: "./Source/Private/Aim.cpp", Depth: 3},
+		{Path: "./Source/Tick.cpp", Depth: 2},
+	}
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"find", ".", "-name", "*.cpp"}, []string{"./Source/Private/Aim.cpp", "./Source/Tick.cpp"}},
+		{[]string{"find", "-type", "d", "-mindepth", "1"}, []string{"./Source", "./Source/Private"}},
+		{[]string{"find", ".", "-maxdepth", "2", "-type", "f"}, []string{"./Source/Aim.h", "./Source/Tick.cpp"}},
+		{[]string{"find", ".", "-path", "*/Private", "-prune", "-o", "-name", "*.cpp", "-print"}, []string{"./Source/Tick.cpp"}},
+		{[]string{"find", ".", "(", "-name", "*.h", "-o", "-name", "Tick*", ")", "!", "-type", "d"}, []string{"./Source/Aim.h", "./Source/Tick.cpp"}},
+		{[]string{"find", ".", "-regex", `.*/\(Aim\|Tick\)\.cpp`}, []string{"./Source/Private/Aim.cpp", "./Source/Tick.cpp"}},
+	}
+	for _, tt := range tests {
+		q := parseFindArgs(tt.args)
+		if q.Unsupported != "" {
+			t.Errorf("%q: unexpected unsupported %q", tt.args, q.Unsupported)
+			continue
+		}
+		if got := q.Run(append([]findCandidate(nil), cands...)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	if q := parseFindArgs([]string{"find", ".", "-name", "*.h", "-exec", "cat", "{}", ";"}); q.Unsupported != "-exec" {
+		t.Errorf("Unsupported = %q, want -exec", q.Unsupported)
+	}
+	if lits, ok := parseFindArgs([]string{"find", ".", "-type", "f", "-name", "Aim*.h"}).Expr.NameLiterals(); !ok || !reflect.DeepEqual(lits, []string{"Aim"}) {
+		t.Errorf("NameLiterals = %q, %v", lits, ok)
+	}
+}
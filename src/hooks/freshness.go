@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io/fs"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ── Staleness guard ──────────────────────────────────────────
+//
+// The watcher ingests edits a few seconds after they land, so a search for
+// code written moments ago can miss it or return the old lines. Before
+// answering from the index, the proxy walks the searched directories for
+// indexed file types modified recently and asks the service which of them it
+// has not ingested yet. If any are stale, the native tool is allowed.
+
+const (
+	// recentEditWindow is how far back a file edit counts as possibly not
+	// ingested. Older files are assumed to be caught up.
+	recentEditWindow = 10 * time.Minute
+	// staleScanBudget bounds the directory walk; a tree too large to walk in
+	// time is assumed fresh rather than delaying every search.
+	staleScanBudget = 150 * time.Millisecond
+	// maxFreshnessChecks caps how many recent files are sent to the service.
+	maxFreshnessChecks = 50
+)
+
+// staleSkipDirs are build output and tool directories the watcher never indexes.
+var staleSkipDirs = map[string]bool{
+	"intermediate": true, "binaries": true, "saved": true, "deriveddatacache": true, "node_modules": true,
+}
+
+// staleInScope reports whether any indexed file type under dirs was modified
+// on disk after the index last ingested it, or is too new to be indexed yet.
+func staleInScope(dirs []string) bool {
+	recent := recentFilesUnder(dirs, time.Now().Add(-recentEditWindow))
+	if len(recent) == 0 {
+		return false
+	}
+
+	// The service knows files by the configured root's spelling, which
+	// differs from the walked one under a symlinked root
+	byService := map[string][]string{}
+	local := map[string]string{}
+	for p := range recent {
+		svcURL := resolveServiceURL(p)
+		sp := serviceSpelling(p)
+		local[sp] = p
+		byService[svcURL] = append(byService[svcURL], sp)
+	}
+	for svcURL, paths := range byService {
+		q := url.Values{"path": paths}
+		// A null mtime is a file the watcher will index but hasn't yet; files
+		// it never indexes are left out of the answer
+		var data struct {
+			Mtimes map[string]*int64 `json:"mtimes"`
+		}
+		if !fetchJSON(svcURL+"/internal/freshness?"+q.Encode(), &data) {
+			// Older services have no freshness endpoint; trust the index
+			continue
+		}
+		for p, indexed := range data.Mtimes {
+			if indexed == nil || recent[local[p]] > *indexed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recentFilesUnder walks dirs and returns indexed file types modified after
+// since, keyed by host path with their mtime in milliseconds. The walk stops
+// at staleScanBudget; non-absolute or missing dirs are skipped.
+func recentFilesUnder(dirs []string, since time.Time) map[string]int64 {
+	recent := map[string]int64{}
+	deadline := time.Now().Add(staleScanBudget)
+	for _, dir := range dirs {
+		if !isAbsShellPath(dir) {
+			continue
+		}
+		filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if time.Now().After(deadline) || len(recent) >= maxFreshnessChecks {
+				return fs.SkipAll
+			}
+			if d.IsDir() {
+				name := d.Name()
+				if p != dir && (strings.HasPrefix(name, ".") || staleSkipDirs[strings.ToLower(name)]) {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if extLanguages[strings.ToLower(filepath.Ext(p))] == "" {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || info.ModTime().Before(since) {
+				return nil
+			}
+			recent[filepath.ToSlash(p)] = info.ModTime().UnixMilli()
+			return nil
+		})
+	}
+	return recent
+}
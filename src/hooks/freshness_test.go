@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRecentFilesUnder(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	for name, mtime := range map[string]time.Time{
+		"Source/Aim.h":             time.Now(),
+		"Source/Tick.cpp":          old,
+		"Source/Notes.bin":         time.Now(),
+		"Intermediate/Build/Gen.h": time.Now(),
+		".git/Hook.py":             time.Now(),
+	} {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for p := range recentFilesUnder([]string{root, "Source"}, time.Now().Add(-recentEditWindow)) {
+		got = append(got, p)
+	}
+	sort.Strings(got)
+	if want := []string{filepath.ToSlash(filepath.Join(root, "Source/Aim.h"))}; !reflect.DeepEqual(got, want) {
+		t.Errorf("recentFilesUnder = %q, want %q", got, want)
+	}
+}
+
+func TestStaleInScopeSymlinkedRoot(t *testing.T) {
+	defer func(p string, projects []indexProject, def string) {
+		breakerStatePath, indexProjects, configuredDefaultURL = p, projects, def
+	}(breakerStatePath, indexProjects, configuredDefaultURL)
+	breakerStatePath = filepath.Join(t.TempDir(), "breaker.json")
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	real := filepath.Join(dir, "Game")
+	link := filepath.Join(dir, "link")
+	if err := os.MkdirAll(filepath.Join(real, "Source"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(real, "Source", "Aim.h"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(real, link); err != nil {
+		t.Skip("symlinks unavailable:", err)
+	}
+	// The service indexed the project through the link
+	indexProjects = []indexProject{{Name: "Game", Paths: []string{real}, IndexedAs: []string{link}}}
+	wantSent := filepath.ToSlash(filepath.Join(link, "Source", "Aim.h"))
+	if got := serviceSpelling(filepath.ToSlash(filepath.Join(real, "Source", "Aim.h"))); got != wantSent {
+		t.Errorf("serviceSpelling = %q, want %q", got, wantSent)
+	}
+
+	var sent []string
+	indexed := time.Now().Add(-time.Hour).UnixMilli()
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = r.URL.Query()["path"]
+		mtimes := map[string]*int64{}
+		for _, p := range sent {
+			if p == wantSent {
+				mtimes[p] = &indexed
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"mtimes": mtimes})
+	}))
+	defer svc.Close()
+	configuredDefaultURL = svc.URL
+
+	// The hook resolves the cwd, so the walk sees the real spelling
+	if !staleInScope([]string{canonicalPath(link)}) {
+		t.Error("a file edited after its indexed mtime under a symlinked root is not stale")
+	}
+	if !reflect.DeepEqual(sent, []string{wantSent}) {
+		t.Errorf("sent %q, want the configured spelling %q", sent, wantSent)
+	}
+}
+
+func TestStaleInScopeMixedDirectory(t *testing.T) {
+	defer func(p string, def string) {
+		breakerStatePath, configuredDefaultURL = p, def
+	}(breakerStatePath, configuredDefaultURL)
+	breakerStatePath = filepath.Join(t.TempDir(), "breaker.json")
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexedFile := filepath.ToSlash(filepath.Join(dir, "Aim.h"))
+	otherFile := filepath.ToSlash(filepath.Join(dir, "Scratch.cpp"))
+	for _, p := range []string{indexedFile, otherFile} {
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The service answers for the file it indexed and leaves out the one its
+	// filters skip, or reports it as not yet ingested
+	var answer map[string]*int64
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"mtimes": answer})
+	}))
+	defer svc.Close()
+	configuredDefaultURL = svc.URL
+
+	ingested := time.Now().Add(time.Minute).UnixMilli()
+	answer = map[string]*int64{indexedFile: &ingested}
+	if staleInScope([]string{dir}) {
+		t.Error("a directory whose indexed file is ingested is stale because of a file the watcher skips")
+	}
+	answer = map[string]*int64{indexedFile: &ingested, otherFile: nil}
+	if !staleInScope([]string{dir}) {
+		t.Error("a directory with a file not yet ingested is not stale")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+)
+
+// ── Glob patterns ────────────────────────────────────────────
+//
+// Glob tool patterns use doublestar semantics: "**" spans any number of path
+// segments, "*" and "?" stay within one segment, [...] classes and {a,b}
+// alternatives (nestable) are supported.
+
+// expandBraces expands {a,b} alternatives, including nested ones, into the
+// list of plain patterns. Unbalanced braces are kept literally.
+func expandBraces(pattern string) []string {
+	open := -1
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth > 0 {
+				continue
+			}
+			prefix, body, suffix := pattern[:open], pattern[open+1:i], pattern[i+1:]
+			var out []string
+			for _, alt := range splitBraceBody(body) {
+				out = append(out, expandBraces(prefix+alt+suffix)...)
+			}
+			return out
+		}
+	}
+	return []string{pattern}
+}
+
+// splitBraceBody splits the inside of a brace group on top-level commas.
+func splitBraceBody(body string) []string {
+	var alts []string
+	depth, start := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alts = append(alts, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alts, body[start:])
+}
+
+// globMatch reports whether a slash-separated relative path matches a
+// brace-free doublestar pattern.
+func globMatch(pattern, p string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(p, "/"))
+}
+
+func matchSegments(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			// Collapse runs of ** and try every split point
+			for len(pat) > 0 && pat[0] == "**" {
+				pat = pat[1:]
+			}
+			if len(pat) == 0 {
+				return true
+			}
+			for i := 0; i < len(segs); i++ {
+				if matchSegments(pat, segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 || !fnmatchAt(pat[0], segs[0]) {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+// globLiteralDir splits a brace-free pattern into its leading wildcard-free
+// directory and the remaining pattern ("Source/Combat", "**/*.h").
+func globLiteralDir(pattern string) (dir, rest string) {
+	segs := strings.Split(pattern, "/")
+	n := 0
+	for n < len(segs)-1 && !strings.ContainsAny(segs[n], "*?[\\") {
+		n++
+	}
+	dir = strings.Join(segs[:n], "/")
+	if n == 1 && segs[0] == "" {
+		dir = "/"
+	}
+	return dir, strings.Join(segs[n:], "/")
+}
+
+// globNameLiterals returns a /find-file term for each alternative's file-name
+// segment, or ok=false if some alternative has no usable literal.
+func globNameLiterals(alts []string) (lits []string, ok bool) {
+	seen := map[string]bool{}
+	for _, alt := range alts {
+		name := alt[strings.LastIndex(alt, "/")+1:]
+		lit := globLiteral(name)
+		if len(lit) < 3 || name == "**" {
+			return nil, false
+		}
+		if !seen[strings.ToLower(lit)] {
+			seen[strings.ToLower(lit)] = true
+			lits = append(lits, lit)
+		}
+	}
+	return lits, true
+}
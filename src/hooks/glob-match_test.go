@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.{h,cpp}", []string{"*.h", "*.cpp"}},
+		{"{Source,Plugins/{A,B}}/*.h", []string{"Source/*.h", "Plugins/A/*.h", "Plugins/B/*.h"}},
+		{"a{b", []string{"a{b"}},
+		{"plain", []string{"plain"}},
+	}
+	for _, tt := range tests {
+		if got := expandBraces(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandBraces(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"**/*.h", "Aim.h", true},
+		{"**/*.h", "Source/Combat/Aim.h", true},
+		{"*.h", "Source/Aim.h", false},
+		{"Source/**/Combat/*Component*.h", "Source/Combat/AimComponent.h", true},
+		{"Source/**/Combat/*Component*.h", "Source/Game/Combat/AimComponent.h", true},
+		{"Source/**/Combat/*Component*.h", "Source/Combat/Private/AimComponent.h", false},
+		{"Source/*", "Source/Combat/Aim.h", false},
+		{"Source/**", "Source/Combat/Aim.h", true},
+		{"Aim?.[ch]", "Aim1.h", true},
+	}
+	for _, tt := range tests {
+		if got := globMatch(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGlobLiterals(t *testing.T) {
+	if dir, rest := globLiteralDir("Source/Combat/**/*.h"); dir != "Source/Combat" || rest != "**/*.h" {
+		t.Errorf("globLiteralDir = %q, %q", dir, rest)
+	}
+	if lits, ok := globNameLiterals([]string{"**/*Component*.h", "**/*Component*.cpp"}); !ok || !reflect.DeepEqual(lits, []string{"Component"}) {
+		t.Errorf("globNameLiterals = %q, %v", lits, ok)
+	}
+	if _, ok := globNameLiterals([]string{"**/*.h"}); ok {
+		t.Errorf("globNameLiterals(**/*.h) should need a listing")
+	}
+}
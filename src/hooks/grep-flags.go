@@ -0,0 +1,508 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ── grep / rg flag translation ───────────────────────────────
+//
+// Maps a shell grep/egrep/fgrep/rg argv onto the /grep parameters
+// (pattern, caseSensitive, contextLines, language) plus the client-side
+// post-processing needed to print what the real command would print.
+
+// grepInvocation is a grep or rg command line in translated form.
+type grepInvocation struct {
+	Tool      string   // grep or rg
+	Patterns  []string // one per -e, or the first operand
+	Operands  []string // files and directories to search
+	Recursive bool     // grep -r/-R; rg always searches directories
+
+	Extended      bool // ERE (grep -E, egrep) or rg syntax; otherwise BRE
+	Fixed         bool // -F: patterns are literal strings
+	CaseSensitive bool
+	SmartCase     bool // rg -S: case-insensitive unless the pattern has uppercase
+	Word          bool // -w
+	WholeLine     bool // -x
+
+	FilesOnly    bool // -l
+	Count        bool // -c
+	CountMatches bool // rg --count-matches: matches per file, not lines
+	OnlyMatching bool // -o
+	LineNumbers  bool // -n (rg -N turns it off)
+	NoFilename   bool // -h / rg -I
+
+	Before, After int // -B / -A (-C sets both)
+	MaxCount      int // -m: matches per file, 0 = unlimited
+
+	Includes    []string // --include / rg -g
+	Excludes    []string // --exclude / rg -g '!…'
+	ExcludeDirs []string // --exclude-dir
+	TypeExts    []string // rg -t: allowed extensions
+	NotTypeExts []string // rg -T: rejected extensions
+
+	Unsupported string // first flag we cannot reproduce from the index
+}
+
+// grepTypeExts maps rg/grep type names to file extensions.
+var grepTypeExts = map[string][]string{
+	"cpp":         {".cpp", ".cc", ".cxx", ".c++", ".h", ".hh", ".hpp", ".hxx", ".inl"},
+	"c":           {".c", ".h"},
+	"h":           {".h", ".hh", ".hpp", ".hxx"},
+	"cs":          {".cs"},
+	"csharp":      {".cs"},
+	"as":          {".as"},
+	"angelscript": {".as"},
+	"ini":         {".ini"},
+	"config":      {".ini"},
+	"json":        {".json"},
+	"py":          {".py"},
+	"md":          {".md"},
+	"markdown":    {".md"},
+	"xml":         {".xml"},
+	"yaml":        {".yaml", ".yml"},
+	"toml":        {".toml"},
+	"txt":         {".txt"},
+}
+
+// extLanguages maps a file extension to the service's language filter.
+var extLanguages = map[string]string{
+	".as":  "angelscript",
+	".cpp": "cpp", ".cc": "cpp", ".h": "cpp", ".hpp": "cpp", ".inl": "cpp",
+	".cs":  "csharp",
+	".ini": "config", ".json": "config", ".uplugin": "config", ".uproject": "config",
+}
+
+// grepShortValueFlags are short flags that consume a value, per tool.
+var grepShortValueFlags = map[string]string{
+	"grep": "efABCmdD",
+	"rg":   "efABCmgtTMErj",
+}
+
+// parseGrepInvocation translates a grep, egrep, fgrep or rg argv.
+func parseGrepInvocation(name string, args []string) grepInvocation {
+	g := grepInvocation{Tool: "grep", CaseSensitive: true}
+	switch name {
+	case "rg":
+		g.Tool = "rg"
+		g.Extended = true
+		g.Recursive = true
+	case "egrep":
+		g.Extended = true
+	case "fgrep":
+		g.Fixed = true
+	}
+	valueFlags := grepShortValueFlags[g.Tool]
+
+	var positional []string
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			positional = append(positional, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "--"):
+			flag, val, hasVal := strings.Cut(arg, "=")
+			if !hasVal && grepLongTakesValue(flag) && i+1 < len(args) {
+				i++
+				val = args[i]
+			}
+			g.applyLong(flag, val)
+		case len(arg) > 1 && arg[0] == '-':
+			// -NUM is grep shorthand for -C NUM
+			if n, err := strconv.Atoi(arg[1:]); err == nil {
+				g.Before, g.After = n, n
+				continue
+			}
+			// Bundled short flags: -rn, -A3, -e PAT
+			for j := 1; j < len(arg); j++ {
+				c := arg[j]
+				if !strings.ContainsRune(valueFlags, rune(c)) {
+					g.applyShort(c)
+					continue
+				}
+				val := arg[j+1:]
+				if val == "" && i+1 < len(args) {
+					i++
+					val = args[i]
+				}
+				g.applyShortValue(c, val)
+				break
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if len(g.Patterns) == 0 && len(positional) > 0 {
+		g.Patterns = []string{positional[0]}
+		positional = positional[1:]
+	}
+	g.Operands = positional
+	if g.SmartCase && g.CaseSensitive {
+		g.CaseSensitive = g.hasUppercase()
+	}
+	return g
+}
+
+func (g *grepInvocation) unsupported(flag string) {
+	if g.Unsupported == "" {
+		g.Unsupported = flag
+	}
+}
+
+func (g *grepInvocation) applyShort(c byte) {
+	rg := g.Tool == "rg"
+	switch c {
+	case 'i', 'y':
+		g.CaseSensitive = false
+		g.SmartCase = false
+	case 's':
+		if rg {
+			g.CaseSensitive = true
+			g.SmartCase = false
+		}
+	case 'S':
+		if rg {
+			g.SmartCase = true
+		}
+	case 'w':
+		g.Word = true
+	case 'x':
+		g.WholeLine = true
+	case 'F':
+		g.Fixed = true
+	case 'E', 'P':
+		g.Extended = true
+	case 'G':
+		g.Extended = g.Tool == "rg"
+	case 'l':
+		g.FilesOnly = true
+	case 'c':
+		g.Count = true
+	case 'o':
+		g.OnlyMatching = true
+	case 'n':
+		g.LineNumbers = true
+	case 'N':
+		if rg {
+			g.LineNumbers = false
+		}
+	case 'h':
+		if !rg {
+			g.NoFilename = true
+		}
+	case 'I':
+		if rg {
+			g.NoFilename = true
+		}
+	case 'L':
+		// rg -L follows symlinks; grep -L lists files without a match
+		if !rg {
+			g.unsupported("-L")
+		}
+	case 'z', 'U':
+		// grep -z / rg -U change what a "line" is
+		if rg == (c == 'U') {
+			g.unsupported("-" + string(c))
+		}
+	case 'r', 'R':
+		if !rg {
+			g.Recursive = true
+		}
+	case 'v', 'q', 'b':
+		g.unsupported("-" + string(c))
+	}
+	// Anything else (-H, -a, -u, -.) only affects hidden files or binary
+	// handling, which the index already decides.
+}
+
+func (g *grepInvocation) applyShortValue(c byte, val string) {
+	switch c {
+	case 'e':
+		g.Patterns = append(g.Patterns, val)
+	case 'A':
+		g.After, _ = strconv.Atoi(val)
+	case 'B':
+		g.Before, _ = strconv.Atoi(val)
+	case 'C':
+		n, _ := strconv.Atoi(val)
+		g.Before, g.After = n, n
+	case 'm':
+		g.MaxCount, _ = strconv.Atoi(val)
+	case 'd':
+		g.Recursive = val == "recurse"
+	case 'g':
+		g.addRgGlob(val)
+	case 't':
+		g.TypeExts = append(g.TypeExts, grepTypeExtsFor(val)...)
+	case 'T':
+		g.NotTypeExts = append(g.NotTypeExts, grepTypeExtsFor(val)...)
+	case 'f':
+		g.unsupported("-f")
+	case 'r':
+		g.unsupported("-r")
+	}
+}
+
+func (g *grepInvocation) applyLong(flag, val string) {
+	switch flag {
+	case "--regexp":
+		g.Patterns = append(g.Patterns, val)
+	case "--ignore-case":
+		g.applyShort('i')
+	case "--case-sensitive":
+		g.applyShort('s')
+	case "--smart-case":
+		g.applyShort('S')
+	case "--word-regexp":
+		g.Word = true
+	case "--line-regexp":
+		g.WholeLine = true
+	case "--fixed-strings":
+		g.Fixed = true
+	case "--extended-regexp", "--perl-regexp", "--pcre2":
+		g.Extended = true
+	case "--files-with-matches":
+		g.FilesOnly = true
+	case "--count":
+		g.Count = true
+	case "--count-matches":
+		g.CountMatches = true
+	case "--recursive", "--dereference-recursive":
+		g.Recursive = true
+	case "--directories":
+		g.Recursive = val == "recurse"
+	case "--only-matching":
+		g.OnlyMatching = true
+	case "--line-number":
+		g.LineNumbers = true
+	case "--no-line-number":
+		g.LineNumbers = false
+	case "--no-filename":
+		g.NoFilename = true
+	case "--after-context":
+		g.After, _ = strconv.Atoi(val)
+	case "--before-context":
+		g.Before, _ = strconv.Atoi(val)
+	case "--context":
+		n, _ := strconv.Atoi(val)
+		g.Before, g.After = n, n
+	case "--max-count":
+		g.MaxCount, _ = strconv.Atoi(val)
+	case "--include":
+		g.Includes = append(g.Includes, val)
+	case "--exclude":
+		g.Excludes = append(g.Excludes, val)
+	case "--exclude-dir":
+		g.ExcludeDirs = append(g.ExcludeDirs, val)
+	case "--glob", "--iglob":
+		g.addRgGlob(val)
+	case "--type":
+		g.TypeExts = append(g.TypeExts, grepTypeExtsFor(val)...)
+	case "--type-not":
+		g.NotTypeExts = append(g.NotTypeExts, grepTypeExtsFor(val)...)
+	case "--invert-match", "--files-without-match", "--quiet", "--file", "--replace", "--files",
+		"--json", "--multiline", "--byte-offset", "--null-data", "--passthru":
+		g.unsupported(flag)
+	}
+}
+
+func (g *grepInvocation) addRgGlob(glob string) {
+	if strings.HasPrefix(glob, "!") {
+		g.Excludes = append(g.Excludes, glob[1:])
+	} else {
+		g.Includes = append(g.Includes, glob)
+	}
+}
+
+func grepLongTakesValue(flag string) bool {
+	switch flag {
+	case "--regexp", "--file", "--after-context", "--before-context", "--context", "--max-count",
+		"--include", "--exclude", "--exclude-dir", "--directories", "--glob", "--iglob", "--type", "--type-not",
+		"--max-depth", "--max-columns", "--threads", "--encoding", "--color", "--colors", "--replace":
+		return true
+	}
+	return false
+}
+
+// grepTypeExtsFor returns the extensions for an rg type name; unknown names
+// are treated as a bare extension (-t as → .as).
+func grepTypeExtsFor(typ string) []string {
+	if exts, ok := grepTypeExts[strings.ToLower(typ)]; ok {
+		return exts
+	}
+	return []string{"." + strings.ToLower(typ)}
+}
+
+func (g grepInvocation) hasUppercase() bool {
+	for _, p := range g.Patterns {
+		if strings.ToLower(p) != p {
+			return true
+		}
+	}
+	return false
+}
+
+// Regex returns the combined pattern in the ERE/RE2 syntax /grep expects:
+// BRE converted, fixed strings escaped, -w/-x applied, multiple -e joined as alternation.
+func (g grepInvocation) Regex() string {
+	var parts []string
+	for _, p := range g.Patterns {
+		switch {
+		case g.Fixed:
+			p = regexp.QuoteMeta(p)
+		case !g.Extended:
+			p = breToERE(p)
+		}
+		parts = append(parts, p)
+	}
+	var re string
+	if len(parts) == 1 {
+		re = parts[0]
+	} else {
+		for i, p := range parts {
+			parts[i] = "(?:" + p + ")"
+		}
+		re = strings.Join(parts, "|")
+	}
+	switch {
+	case g.WholeLine:
+		re = "^(?:" + re + ")$"
+	case g.Word:
+		re = `\b(?:` + re + `)\b`
+	}
+	return re
+}
+
+// breToERE converts a POSIX basic regular expression to extended syntax:
+// \| \( \) \+ \? \{ \} become operators and their bare forms become literals.
+func breToERE(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c == '\\' && i+1 < len(p) {
+			next := p[i+1]
+			if strings.IndexByte("|()+?{}", next) >= 0 {
+				b.WriteByte(next)
+			} else {
+				b.WriteByte(c)
+				b.WriteByte(next)
+			}
+			i++
+			continue
+		}
+		if strings.IndexByte("|()+?{}", c) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// Language returns the /grep language filter implied by --include/-g/-t, or ""
+// when the allowed extensions span several languages (or none are given).
+func (g grepInvocation) Language() string {
+	var langs []string
+	// Type lists name extensions the index may not cover (.cxx) — skip those
+	for _, ext := range g.TypeExts {
+		if l := extLanguages[ext]; l != "" {
+			langs = append(langs, l)
+		}
+	}
+	for _, inc := range g.Includes {
+		for _, alt := range expandBraces(inc) {
+			ext := strings.ToLower(path.Ext(alt))
+			l := extLanguages[ext]
+			if l == "" || strings.ContainsAny(ext, "*?[") {
+				return ""
+			}
+			langs = append(langs, l)
+		}
+	}
+	lang := ""
+	for _, l := range langs {
+		if lang != "" && l != lang {
+			return ""
+		}
+		lang = l
+	}
+	return lang
+}
+
+// HasFilters reports whether results need client-side file filtering.
+func (g grepInvocation) HasFilters() bool {
+	return len(g.Includes)+len(g.Excludes)+len(g.ExcludeDirs)+len(g.TypeExts)+len(g.NotTypeExts) > 0
+}
+
+// Keep reports whether a result file passes the include/exclude/type filters.
+func (g grepInvocation) Keep(file string) bool {
+	file = strings.ReplaceAll(file, "\\", "/")
+	base := path.Base(file)
+	ext := strings.ToLower(path.Ext(base))
+	if len(g.TypeExts) > 0 && !containsString(g.TypeExts, ext) {
+		return false
+	}
+	if containsString(g.NotTypeExts, ext) {
+		return false
+	}
+	if len(g.Includes) > 0 {
+		ok := false
+		for _, inc := range g.Includes {
+			if matchFileGlob(inc, file) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, exc := range g.Excludes {
+		if matchFileGlob(exc, file) {
+			return false
+		}
+	}
+	for _, dir := range g.ExcludeDirs {
+		segs := strings.Split(path.Dir(file), "/")
+		for _, seg := range segs {
+			if ok, _ := path.Match(dir, seg); ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchFileGlob matches a grep/rg glob: patterns without a slash match the
+// basename, patterns with one match trailing path segments ("**" and {a,b}
+// alternatives included).
+func matchFileGlob(glob, file string) bool {
+	for _, alt := range expandBraces(strings.ReplaceAll(glob, "\\", "/")) {
+		alt = strings.TrimPrefix(alt, "**/")
+		if !strings.Contains(alt, "/") {
+			if fnmatchAt(alt, path.Base(file)) {
+				return true
+			}
+			continue
+		}
+		segs := strings.Split(file, "/")
+		for i := range segs {
+			if globMatch(alt, strings.Join(segs[i:], "/")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
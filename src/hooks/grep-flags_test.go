@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGrepInvocation(t *testing.T) {
+	g := parseGrepInvocation("grep", []string{"grep", "-rniw", "-A", "2", "-B1", "--include=*.h", "-e", "Foo", "-e", "Bar", "Source", "Plugins"})
+	if !reflect.DeepEqual(g.Patterns, []string{"Foo", "Bar"}) || !reflect.DeepEqual(g.Operands, []string{"Source", "Plugins"}) {
+		t.Fatalf("patterns/operands = %q / %q", g.Patterns, g.Operands)
+	}
+	if g.CaseSensitive || !g.Word || !g.LineNumbers || g.After != 2 || g.Before != 1 {
+		t.Errorf("flags not applied: %+v", g)
+	}
+	if got, want := g.Regex(), `\b(?:(?:Foo)|(?:Bar))\b`; got != want {
+		t.Errorf("Regex() = %q, want %q", got, want)
+	}
+	if got := g.Language(); got != "cpp" {
+		t.Errorf("Language() = %q, want cpp", got)
+	}
+
+	rg := parseGrepInvocation("rg", []string{"rg", "-t", "cpp", "-g", "!*Test*", "-S", "-l", "foo"})
+	if !rg.FilesOnly || rg.CaseSensitive || rg.Language() != "cpp" {
+		t.Errorf("rg flags not applied: %+v", rg)
+	}
+	if !rg.Keep("Game/Source/Aim.h") || rg.Keep("Game/Source/AimTest.cpp") || rg.Keep("Game/Script/Aim.as") {
+		t.Errorf("rg Keep filters wrong")
+	}
+
+	if v := parseGrepInvocation("grep", []string{"grep", "-v", "foo"}); v.Unsupported != "-v" {
+		t.Errorf("Unsupported = %q, want -v", v.Unsupported)
+	}
+	if c := parseGrepInvocation("rg", []string{"rg", "--count-matches", "foo"}); c.Count || !c.CountMatches {
+		t.Errorf("--count-matches parsed as a line count: %+v", c)
+	}
+
+	// grep only descends into directories when asked to; rg always does
+	for _, tt := range []struct {
+		args      []string
+		recursive bool
+	}{
+		{[]string{"grep", "foo", "Source"}, false},
+		{[]string{"grep", "-rn", "foo", "Source"}, true},
+		{[]string{"grep", "-R", "foo"}, true},
+		{[]string{"grep", "--recursive", "foo"}, true},
+		{[]string{"grep", "-d", "recurse", "foo"}, true},
+		{[]string{"rg", "foo", "Source"}, true},
+	} {
+		if got := parseGrepInvocation(tt.args[0], tt.args).Recursive; got != tt.recursive {
+			t.Errorf("%q Recursive = %v, want %v", tt.args, got, tt.recursive)
+		}
+	}
+}
+
+func TestGrepRegex(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"grep", `Foo\|Bar(`}, `Foo|Bar\(`},
+		{[]string{"grep", "-E", `Foo|Bar\(`}, `Foo|Bar\(`},
+		{[]string{"fgrep", "a.b*"}, `a\.b\*`},
+		{[]string{"rg", "-x", "-F", "x+y"}, `^(?:x\+y)$`},
+	}
+	for _, tt := range tests {
+		if got := parseGrepInvocation(tt.args[0], tt.args).Regex(); got != tt.want {
+			t.Errorf("%q Regex() = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ── Host path normalization ──────────────────────────────────
+//
+// The same directory reaches the proxy in several spellings: D:\Game from
+// Windows tools, /d/Game from Git Bash, /mnt/d/Game from WSL, \\wsl$\Ubuntu\...
+// from Windows looking into WSL. Paths are compared in one canonical form:
+// forward slashes, drive letters as "d:", and case folded only where the
+// filesystem ignores case (Windows volumes and shares, macOS).
+
+// hostOS is the operating system whose path rules apply; tests override it.
+var hostOS = runtime.GOOS
+
+// normalizePath converts p to the canonical form used for prefix matching.
+// It does not touch the filesystem; see canonicalPath for symlinks.
+func normalizePath(p string) string {
+	s := strings.ReplaceAll(p, "\\", "/")
+	// Win32 extended-length prefix: //?/D:/path, //?/UNC/server/share
+	if rest, ok := strings.CutPrefix(s, "//?/"); ok {
+		if unc, ok := strings.CutPrefix(rest, "UNC/"); ok {
+			rest = "//" + unc
+		}
+		s = rest
+	}
+	s = strings.TrimRight(s, "/")
+
+	foldCase := hostOS == "windows" || hostOS == "darwin"
+	switch {
+	case isWSLSharePath(s):
+		// \\wsl$\Distro\home\x is /home/x inside the distro (case-sensitive)
+		parts := strings.SplitN(s, "/", 5)
+		s = ""
+		if len(parts) == 5 {
+			s = "/" + parts[4]
+		}
+		foldCase = false
+	case strings.HasPrefix(s, "//"):
+		// Other UNC shares are Windows shares
+		foldCase = true
+	case isDriveRoot(s, "/mnt/"):
+		// WSL mount: /mnt/d/path → d:/path
+		s = strings.ToLower(s[5:6]) + ":" + s[6:]
+		foldCase = true
+	case hostOS == "windows" && isDriveRoot(s, "/"):
+		// Git Bash: /d/path → d:/path
+		s = strings.ToLower(s[1:2]) + ":" + s[2:]
+		foldCase = true
+	case len(s) >= 2 && s[1] == ':' && isASCIILetter(s[0]):
+		foldCase = true
+	}
+	if foldCase {
+		s = strings.ToLower(s)
+	}
+	return s
+}
+
+// isDriveRoot reports whether s is mount followed by a single drive letter
+// and then the end of the path or a slash (/mnt/d, /mnt/d/Game).
+func isDriveRoot(s, mount string) bool {
+	rest, ok := strings.CutPrefix(s, mount)
+	return ok && len(rest) >= 1 && isASCIILetter(rest[0]) && (len(rest) == 1 || rest[1] == '/')
+}
+
+// isWSLSharePath reports whether s is a //wsl$/Distro or //wsl.localhost/Distro
+// share path.
+func isWSLSharePath(s string) bool {
+	parts := strings.SplitN(s, "/", 5)
+	if len(parts) < 4 || parts[0] != "" || parts[1] != "" || parts[3] == "" {
+		return false
+	}
+	host := strings.ToLower(parts[2])
+	return host == "wsl$" || host == "wsl.localhost"
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// canonicalPath resolves symlinks and junctions in an absolute host path so a
+// linked checkout routes like its target. Trailing components that do not
+// exist are kept as given; paths that cannot be resolved are returned as is.
+func canonicalPath(p string) string {
+	if !filepath.IsAbs(p) {
+		return p
+	}
+	dir, rest := filepath.Clean(p), ""
+	for {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			if real == dir {
+				return p
+			}
+			return filepath.Join(real, rest)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return p
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+		dir = parent
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizePath(t *testing.T) {
+	defer func(goos string) { hostOS = goos }(hostOS)
+
+	tests := []struct {
+		goos, path, want string
+	}{
+		{"windows", `D:\Game\Source\`, "d:/game/source"},
+		{"windows", "/d/Game/Source", "d:/game/source"},
+		{"windows", `\\?\D:\Game`, "d:/game"},
+		{"windows", `\\Build\Share\Game`, "//build/share/game"},
+		{"windows", `\\wsl$\Ubuntu\home\dev\Game`, "/home/dev/Game"},
+		{"linux", "/mnt/d/Game/Source", "d:/game/source"},
+		{"linux", `D:\Game`, "d:/game"},
+		{"linux", "/home/dev/Game", "/home/dev/Game"},
+		{"linux", "/d/Game", "/d/Game"}, // a real directory, not Git Bash
+		{"linux", "//wsl.localhost/Ubuntu", ""},
+		{"darwin", "/Users/dev/Game", "/users/dev/game"},
+	}
+	for _, tt := range tests {
+		hostOS = tt.goos
+		if got := normalizePath(tt.path); got != tt.want {
+			t.Errorf("%s: normalizePath(%q) = %q, want %q", tt.goos, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalPath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	real := filepath.Join(dir, "Game")
+	link := filepath.Join(dir, "link")
+	if err := os.Mkdir(real, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(real, link); err != nil {
+		t.Skip("symlinks unavailable:", err)
+	}
+
+	tests := []struct {
+		path, want string
+	}{
+		{link, real},
+		{filepath.Join(link, "Source", "Missing.h"), filepath.Join(real, "Source", "Missing.h")},
+		{real, real},
+		{"Source", "Source"},
+	}
+	for _, tt := range tests {
+		if got := canonicalPath(tt.path); got != tt.want {
+			t.Errorf("canonicalPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
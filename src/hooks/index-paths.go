@@ -0,0 +1,242 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// ── Index path ↔ host path mapping ───────────────────────────
+//
+// The service returns cleaned paths: "Project/relative/path" (relative to a
+// project root) or, for some listings, paths relative to the workspace-wide
+// common prefix. The proxy maps them back onto host paths using the project
+// roots that install.js writes to unreal-index-paths.json.
+
+// indexProject is one indexed project from unreal-index-paths.json. Paths
+// have symlinks resolved, like the cwd and tool paths they are matched
+// against; IndexedAs holds the configured roots the service stores files
+// under, when that spelling differs.
+type indexProject struct {
+	Name      string   `json:"name"`
+	Paths     []string `json:"paths"`
+	IndexedAs []string `json:"indexedAs,omitempty"`
+	Port      int      `json:"port"`
+}
+
+var indexProjects []indexProject
+
+// slashPath converts backslashes to forward slashes and strips trailing slashes,
+// keeping the original case.
+func slashPath(p string) string {
+	s := strings.ReplaceAll(p, "\\", "/")
+	if len(s) > 1 {
+		s = strings.TrimRight(s, "/")
+	}
+	return s
+}
+
+// relUnder returns p relative to base if p is base or lies below it, comparing
+// whole path segments. The returned relative path keeps p's case.
+func relUnder(base, p string) (string, bool) {
+	if base == "" || p == "" {
+		return "", false
+	}
+	nb, np := normalizePath(base), normalizePath(p)
+	if np == nb {
+		return "", true
+	}
+	if !strings.HasPrefix(np, nb+"/") {
+		return "", false
+	}
+	rel := np[len(nb)+1:]
+	// Prefer the original spelling; normalization only rewrites the drive or
+	// share head, so the tail lines up with the end of p
+	if sp := slashPath(p); len(sp) >= len(rel) && strings.EqualFold(sp[len(sp)-len(rel):], rel) {
+		rel = sp[len(sp)-len(rel):]
+	}
+	return rel, true
+}
+
+// commonDir returns the longest common directory of the given paths, segment-wise.
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	common := strings.Split(slashPath(paths[0]), "/")
+	for _, p := range paths[1:] {
+		segs := strings.Split(slashPath(p), "/")
+		n := 0
+		for n < len(common) && n < len(segs) && strings.EqualFold(common[n], segs[n]) {
+			n++
+		}
+		common = common[:n]
+	}
+	return strings.Join(common, "/")
+}
+
+// projectBases returns the host directories an index path of this project can
+// be relative to: each configured root, then their common directory.
+func projectBases(p indexProject) []string {
+	var bases []string
+	for _, path := range p.Paths {
+		bases = append(bases, slashPath(path))
+	}
+	if len(p.Paths) > 1 {
+		if c := commonDir(p.Paths); c != "" {
+			bases = append(bases, c)
+		}
+	}
+	return bases
+}
+
+// hostPath maps an index path back to a host path. When several roots could
+// hold the file, the first one that exists on disk wins. Returns "" if no
+// project mapping is configured for the path.
+func hostPath(indexPath string) string {
+	indexPath = slashPath(indexPath)
+	if isAbsShellPath(indexPath) {
+		return indexPath
+	}
+	var candidates []string
+	if name, rest, ok := strings.Cut(indexPath, "/"); ok {
+		for _, p := range indexProjects {
+			if p.Name == name {
+				for _, base := range projectBases(p) {
+					candidates = append(candidates, base+"/"+rest)
+				}
+			}
+		}
+	}
+	// Paths relative to the workspace-wide common prefix (no project segment)
+	byPort := map[int][]string{}
+	var ports []int
+	for _, p := range indexProjects {
+		if _, seen := byPort[p.Port]; !seen {
+			ports = append(ports, p.Port)
+		}
+		byPort[p.Port] = append(byPort[p.Port], p.Paths...)
+	}
+	for _, port := range ports {
+		if c := commonDir(byPort[port]); c != "" {
+			candidates = append(candidates, c+"/"+indexPath)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return ""
+	}
+	if len(candidates) > 1 {
+		for _, c := range candidates {
+			if _, err := os.Stat(c); err == nil {
+				return c
+			}
+		}
+	}
+	return candidates[0]
+}
+
+// indexLocation is a host path located inside a project root.
+type indexLocation struct {
+	Project indexProject
+	Base    string // the configured root containing the path
+	Rel     string // path relative to Base ("" for the root itself)
+}
+
+// Module returns the dotted module name the indexer derives for this directory.
+func (l indexLocation) Module() string {
+	if l.Rel == "" {
+		return l.Project.Name
+	}
+	return l.Project.Name + "." + strings.ReplaceAll(l.Rel, "/", ".")
+}
+
+// locateHostPath finds the most specific project root containing p.
+func locateHostPath(p string) (indexLocation, bool) {
+	var best indexLocation
+	bestLen := -1
+	for _, proj := range indexProjects {
+		for _, base := range proj.Paths {
+			rel, ok := relUnder(base, p)
+			if ok && len(normalizePath(base)) > bestLen {
+				bestLen = len(normalizePath(base))
+				best = indexLocation{Project: proj, Base: slashPath(base), Rel: rel}
+			}
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// serviceSpelling maps a host path onto the configured project root the
+// service stores it under, for roots install.js resolved through a symlink.
+// Other paths are returned unchanged.
+func serviceSpelling(p string) string {
+	loc, ok := locateHostPath(p)
+	if !ok {
+		return p
+	}
+	for i, base := range loc.Project.Paths {
+		if slashPath(base) != loc.Base || i >= len(loc.Project.IndexedAs) {
+			continue
+		}
+		indexed := slashPath(loc.Project.IndexedAs[i])
+		if indexed == loc.Base {
+			return p
+		}
+		if loc.Rel == "" {
+			return indexed
+		}
+		return indexed + "/" + loc.Rel
+	}
+	return p
+}
+
+// projectRootsUnder returns the project roots that lie at or below dir, for
+// start directories above any single project (e.g. the game root).
+func projectRootsUnder(dir string) []indexLocation {
+	var locs []indexLocation
+	for _, proj := range indexProjects {
+		for _, base := range proj.Paths {
+			if _, ok := relUnder(dir, base); ok {
+				locs = append(locs, indexLocation{Project: proj, Base: slashPath(base)})
+			}
+		}
+	}
+	return locs
+}
+
+// scopeDirs resolves a tool's path argument to host directories. Relative
+// paths are taken against each project root, since the caller's working
+// directory is unknown.
+func scopeDirs(p string) []string {
+	if p == "" {
+		return nil
+	}
+	if isAbsShellPath(p) {
+		return []string{slashPath(p)}
+	}
+	var dirs []string
+	for _, proj := range indexProjects {
+		for _, root := range proj.Paths {
+			dirs = append(dirs, joinShellPath(slashPath(root), p))
+		}
+	}
+	return dirs
+}
+
+// inScope reports whether an index path lies under one of the host
+// directories. Paths that cannot be mapped to the host are kept.
+func inScope(indexPath string, dirs []string) bool {
+	if len(dirs) == 0 {
+		return true
+	}
+	hp := hostPath(indexPath)
+	if hp == "" {
+		return true
+	}
+	for _, dir := range dirs {
+		if _, ok := relUnder(dir, hp); ok {
+			return true
+		}
+	}
+	return false
+}
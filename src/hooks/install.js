@@ -1,10 +1,11 @@
 #!/usr/bin/env node
 
-// Standalone installer for unreal-index PreToolUse hooks.
+// Standalone installer for unreal-index hooks (PreToolUse, PostToolUse, SessionStart,
+// UserPromptSubmit).
 // Deploys the proxy binary (Go or Node.js fallback) to a project's .claude/hooks/,
 // updates .claude/settings.json with hook config, and adds search instructions to CLAUDE.local.md.
 
-import { existsSync, mkdirSync, readFileSync, writeFileSync, copyFileSync } from 'fs';
+import { existsSync, mkdirSync, readFileSync, writeFileSync, copyFileSync, realpathSync } from 'fs';
 import { join, dirname, resolve } from 'path';
 import { execSync } from 'child_process';
 import { fileURLToPath } from 'url';
@@ -40,14 +41,89 @@ function toWindowsPath(p) {
   return p;
 }
 
-/** Normalize a path for prefix matching (matches proxy normalization). */
+/**
+ * Resolve symlinks and junctions in a project path, once at install time, so
+ * the proxy doesn't walk every component of every path on each hook call (it
+ * only canonicalizes the cwd and tool paths). The path keeps its original
+ * style: Windows paths are resolved through their /mnt mount from WSL.
+ * Paths that cannot be resolved are returned as given.
+ */
+function canonicalPath(p) {
+  const local = toNativePath(p);
+  try {
+    const real = realpathSync.native(local);
+    if (real === local.replace(/[\\/]+$/, '')) return p;
+    return local !== p ? toWindowsPath(real) : real;
+  } catch {
+    return p;
+  }
+}
+
+/**
+ * A project entry for the proxy: roots resolved like the cwd and tool paths
+ * they are matched against, plus the configured roots as indexedAs when those
+ * differ, since the service stores files under the configured spelling.
+ */
+function proxyProject(p, port) {
+  const configured = p.paths || [];
+  const paths = configured.map(canonicalPath);
+  return {
+    name: p.name,
+    paths,
+    ...(paths.some((r, i) => r !== configured[i]) && { indexedAs: configured }),
+    ...(port && { port }),
+  };
+}
+
+/** Routing prefixes for project roots: resolved, then as configured. */
+function rootPrefixes(paths) {
+  return [...new Set(paths.flatMap(p => [canonicalPath(p), p]))];
+}
+
+// The proxy binary is built for Windows when installing from WSL, so it
+// normalizes paths by Windows rules there.
+const proxyPlatform = isWSL ? 'win32' : process.platform;
+
+/**
+ * Normalize a path for prefix matching (matches normalizePath in the proxy):
+ * forward slashes, /mnt/d and Git Bash /d as d:, \\wsl$ shares as POSIX paths,
+ * and case folded only on case-insensitive filesystems.
+ */
 function normalizePath(p) {
-  let s = p.replace(/\\/g, '/').toLowerCase().replace(/\/+$/, '');
-  // Git Bash: /d/path → d:/path
-  if (s.length >= 3 && s[0] === '/' && s[2] === '/' && s[1] >= 'a' && s[1] <= 'z') {
-    s = s[1] + ':' + s.slice(2);
+  let s = p.replace(/\\/g, '/');
+  if (s.startsWith('//?/')) {
+    s = s.slice(4);
+    if (s.startsWith('UNC/')) s = '//' + s.slice(4);
+  }
+  s = s.replace(/\/+$/, '');
+
+  let foldCase = proxyPlatform === 'win32' || proxyPlatform === 'darwin';
+  let m;
+  if ((m = s.match(/^\/\/(wsl\$|wsl\.localhost)\/[^/]+(\/.*)?$/i))) {
+    s = m[2] || '';
+    foldCase = false;
+  } else if (s.startsWith('//')) {
+    foldCase = true;
+  } else if ((m = s.match(/^\/mnt\/([A-Za-z])(\/.*)?$/))) {
+    s = m[1].toLowerCase() + ':' + (m[2] || '');
+    foldCase = true;
+  } else if (proxyPlatform === 'win32' && (m = s.match(/^\/([A-Za-z])(\/.*)?$/))) {
+    s = m[1].toLowerCase() + ':' + (m[2] || '');
+    foldCase = true;
+  } else if (/^[A-Za-z]:/.test(s)) {
+    foldCase = true;
   }
-  return s;
+  return foldCase ? s.toLowerCase() : s;
+}
+
+// Rank how well a normalized workspace prefix matches a normalized path by
+// whole segments: a prefix containing the path scores its length + 1, a prefix
+// below the path scores 0, no overlap is -1. Mirrors routeScore in the Go
+// proxy so both pick the same workspace for a path.
+function routeScore(path, prefix) {
+  if (path === prefix || prefix === '' || path.startsWith(prefix + '/')) return prefix.length + 1;
+  if (path === '' || prefix.startsWith(path + '/')) return 0;
+  return -1;
 }
 
 // ── Main install function ────────────────────────────────────
@@ -70,7 +146,6 @@ export async function installHooks(projectDir, { silent = false, tryGo = true }
 
   let proxyCommand;
   let compiled = false;
-  const goSource = join(__dirname, 'unreal-index-proxy.go');
 
   if (tryGo) {
     try {
@@ -79,7 +154,9 @@ export async function installHooks(projectDir, { silent = false, tryGo = true }
       const targetExe = join(hooksDir, 'unreal-index-proxy.exe');
       // Cross-compile for Windows when running from WSL
       const envPrefix = isWSL ? 'GOOS=windows GOARCH=amd64 ' : '';
-      execSync(`${envPrefix}go build -o "${targetExe}" "${goSource}"`, {
+      // The proxy is the main package of the module in this directory; its
+      // one dependency, the SQLite driver for offline lookups, is pure Go
+      execSync(`${envPrefix}go build -o "${targetExe}" .`, {
         stdio: 'pipe',
         timeout: 60000,
         cwd: __dirname,
@@ -116,7 +193,9 @@ export async function installHooks(projectDir, { silent = false, tryGo = true }
       wsConfig = JSON.parse(readFileSync(workspacesPath, 'utf-8'));
       const allPrefixes = [];
       const workspaces = [];
-      const normalizedProjectDir = normalizePath(projectDir);
+      const projects = [];
+      const normalizedProjectDir = normalizePath(canonicalPath(projectDir));
+      const tieBreak = wsConfig.shared?.hooks?.routingTieBreak || 'default';
 
       // Build workspace list and detect which workspace owns the project directory
       let bestMatchLen = -1;
@@ -126,22 +205,30 @@ export async function installHooks(projectDir, { silent = false, tryGo = true }
         if (existsSync(wsConfigPath)) {
           try {
             const cfg = JSON.parse(readFileSync(wsConfigPath, 'utf-8'));
-            prefixes = (cfg.projects || []).flatMap(p => p.paths || []);
+            prefixes = rootPrefixes((cfg.projects || []).flatMap(p => p.paths || []));
+            // Project names + roots let the proxy map index paths (Project/rel) back to host paths
+            for (const p of cfg.projects || []) {
+              projects.push(proxyProject(p, ws.port));
+            }
           } catch {}
         }
         allPrefixes.push(...prefixes);
-        workspaces.push({ port: ws.port, prefixes });
+        // zoektPort lets the proxy search Zoekt directly while the service is
+        // down, and a dataDir database answers its lookups offline
+        const database = ws.dataDir ? ws.dataDir.replace(/\\/g, '/').replace(/\/+$/, '') + '/index.db' : undefined;
+        workspaces.push({ name, port: ws.port, zoektPort: ws.zoektPort, database, prefixes });
 
-        // Check if this workspace owns the project directory (longest overlap wins)
+        // Check if this workspace owns the project directory (most specific match
+        // wins; ties go to the default workspace unless routingTieBreak is "first")
         for (const prefix of prefixes) {
-          const normalizedPrefix = normalizePath(prefix);
-          if (normalizedProjectDir.startsWith(normalizedPrefix) || normalizedPrefix.startsWith(normalizedProjectDir)) {
-            const matchLen = Math.min(normalizedProjectDir.length, normalizedPrefix.length);
-            if (matchLen > bestMatchLen) {
-              bestMatchLen = matchLen;
-              owningWorkspace = name;
-              owningPort = ws.port;
-            }
+          const matchLen = routeScore(normalizedProjectDir, normalizePath(prefix));
+          if (matchLen < 0) continue;
+          const preferOnTie = matchLen === bestMatchLen && tieBreak !== 'first' &&
+            name === wsConfig.defaultWorkspace && owningWorkspace !== wsConfig.defaultWorkspace;
+          if (matchLen > bestMatchLen || preferOnTie) {
+            bestMatchLen = matchLen;
+            owningWorkspace = name;
+            owningPort = ws.port;
           }
         }
       }
@@ -164,6 +251,8 @@ export async function installHooks(projectDir, { silent = false, tryGo = true }
       const pathsConfig = {
         indexedPrefixes: allPrefixes,
         workspaces,
+        projects,
+        ...(wsConfig.shared?.hooks && { hooks: wsConfig.shared.hooks }),
         ...(owningPort && { defaultPort: owningPort }),
         ...(owningWorkspace && { defaultWorkspace: owningWorkspace }),
       };
@@ -179,8 +268,9 @@ export async function installHooks(projectDir, { silent = false, tryGo = true }
     // Legacy single-config mode
     try {
       const config = JSON.parse(readFileSync(legacyConfigPath, 'utf-8'));
-      const indexedPrefixes = (config.projects || []).flatMap(p => p.paths || []);
-      const pathsConfig = { indexedPrefixes };
+      const indexedPrefixes = rootPrefixes((config.projects || []).flatMap(p => p.paths || []));
+      const projects = (config.projects || []).map(p => proxyProject(p));
+      const pathsConfig = { indexedPrefixes, projects, ...(config.hooks && { hooks: config.hooks }) };
       writeFileSync(
         join(hooksDir, 'unreal-index-paths.json'),
         JSON.stringify(pathsConfig, null, 2) + '\n'
@@ -199,19 +289,28 @@ export async function installHooks(projectDir, { silent = false, tryGo = true }
   }
 
   if (!settings.hooks) settings.hooks = {};
-  if (!settings.hooks.PreToolUse) settings.hooks.PreToolUse = [];
-
-  // Remove any existing unreal-index-proxy hooks (update in place)
-  settings.hooks.PreToolUse = settings.hooks.PreToolUse.filter(h =>
-    !(h.matcher === 'Grep|Glob|Bash' &&
-      h.hooks?.some(hh => (hh.command || '').includes('unreal-index-proxy')))
-  );
-
-  // Add the new hook
-  settings.hooks.PreToolUse.push({
-    matcher: 'Grep|Glob|Bash',
-    hooks: [{ type: 'command', command: proxyCommand }],
-  });
+
+  // Remove any existing unreal-index-proxy hooks (update in place), then add
+  // the current ones
+  const isProxyHook = h => h.hooks?.some(hh => (hh.command || '').includes('unreal-index-proxy'));
+  for (const event of ['PreToolUse', 'PostToolUse', 'SessionStart', 'UserPromptSubmit']) {
+    if (settings.hooks[event]) settings.hooks[event] = settings.hooks[event].filter(h => !isProxyHook(h));
+  }
+  const addHook = (event, matcher) => {
+    if (!settings.hooks[event]) settings.hooks[event] = [];
+    const hooks = [{ type: 'command', command: proxyCommand }];
+    settings.hooks[event].push(matcher ? { matcher, hooks } : { hooks });
+  };
+
+  // Subagent prompts get the index instructions appended and guessed Read
+  // paths are corrected (Go proxy only)
+  addHook('PreToolUse', compiled ? 'Grep|Glob|Bash|Task|Read' : 'Grep|Glob|Bash');
+  // Edited files are pushed to the index right away (Go proxy only)
+  if (compiled) addHook('PostToolUse', 'Edit|Write|MultiEdit');
+  // Live index status and routing at session start (Go proxy only)
+  if (compiled) addHook('SessionStart', 'startup|resume|clear|compact');
+  // Definitions of identifiers named in the prompt (Go proxy only)
+  if (compiled) addHook('UserPromptSubmit');
 
   writeFileSync(settingsPath, JSON.stringify(settings, null, 2) + '\n');
   if (!silent) console.log(`  Updated ${settingsPath}`);
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	_ "modernc.org/sqlite"
+)
+
+// ── Offline lookups from the workspace database ──────────────
+//
+// With the service container stopped every lookup fails, although a
+// workspace with dataDir keeps its SQLite database on the host. install.js
+// records that file as the workspace's database, and when the service
+// doesn't answer, the proxy opens it read-only and answers find-type,
+// find-member and find-file queries itself. The watcher isn't ingesting
+// while the service is down, so these answers are marked offline and
+// possibly stale, and an empty answer is never trusted: the native tool
+// runs instead.
+
+const (
+	offlineLabel   = "offline, possibly stale"
+	offlineListing = offlineLabel + ": listed from the workspace database while its service is down"
+)
+
+var (
+	offlineMu  sync.Mutex
+	offlineDBs = map[string]*sql.DB{}
+)
+
+// offlineDB opens a workspace database read-only, once per hook call.
+// Returns nil if the file can't be opened as an index database.
+func offlineDB(file string) *sql.DB {
+	offlineMu.Lock()
+	defer offlineMu.Unlock()
+	if db, ok := offlineDBs[file]; ok {
+		return db
+	}
+	u := url.URL{Scheme: "file", Path: slashPath(file), RawQuery: "mode=ro"}
+	if strings.Contains(u.Path, ":") && !strings.HasPrefix(u.Path, "/") {
+		// D:/x is a path, not a scheme
+		u.Path = "/" + u.Path
+	}
+	db, err := sql.Open("sqlite", u.String())
+	if err == nil && db.QueryRow("SELECT 1 FROM files LIMIT 1").Err() != nil {
+		db.Close()
+		db = nil
+	}
+	offlineDBs[file] = db
+	return db
+}
+
+// indexLookup is batchLookup with the offline fallback: when the service at
+// svcURL doesn't answer and its workspace database is on the host, the
+// queries are answered from it and offline is set. Methods other than
+// findTypeByName, findMember and findFileByName get a nil result.
+func indexLookup(ctx context.Context, svcURL string, queries []batchQuery) (results []json.RawMessage, offline, ok bool) {
+	results, ok = batchLookup(ctx, svcURL, queries)
+	if ok || ctx.Err() != nil {
+		return results, false, ok
+	}
+	file := routeFor(svcURL).Database
+	if file == "" {
+		return results, false, false
+	}
+	db := offlineDB(file)
+	if db == nil {
+		return results, false, false
+	}
+	for i, q := range queries {
+		results[i] = offlineQuery(ctx, db, q)
+	}
+	return results, true, true
+}
+
+// offlineQuery answers one batch query from the database in the shape the
+// service's /batch returns it, or returns nil.
+func offlineQuery(ctx context.Context, db *sql.DB, q batchQuery) json.RawMessage {
+	if len(q.Args) == 0 {
+		return nil
+	}
+	name, _ := q.Args[0].(string)
+	if name == "" {
+		return nil
+	}
+	limit := 20
+	if len(q.Args) > 1 {
+		if opts, ok := q.Args[1].(map[string]interface{}); ok {
+			if n, ok := opts["maxResults"].(int); ok && n > 0 {
+				limit = n
+			}
+		}
+	}
+	var result interface{}
+	var err error
+	switch q.Method {
+	case "findTypeByName":
+		result, err = offlineFindType(ctx, db, name, limit)
+	case "findMember":
+		result, err = offlineFindMember(ctx, db, name, limit)
+	case "findFileByName":
+		result, err = offlineFindFile(ctx, db, name, limit)
+	default:
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// offlineFindType finds types by exact name, headers first, then by the
+// Unreal prefix variants (Actor → AActor) as the service does.
+func offlineFindType(ctx context.Context, db *sql.DB, name string, limit int) ([]FindTypeResult, error) {
+	names := []string{name}
+	results, err := queryTypes(ctx, db, names, limit)
+	if err != nil || len(results) > 0 {
+		return results, err
+	}
+	names = names[:0]
+	bare := strings.TrimLeft(name[:1], "UAFESI") + name[1:]
+	for _, prefix := range []string{"A", "U", "F", "E", "S", "I", ""} {
+		if v := prefix + name; prefix != "" && v != name {
+			names = append(names, v)
+		}
+		if v := prefix + bare; bare != name && v != name {
+			names = append(names, v)
+		}
+	}
+	return queryTypes(ctx, db, names, limit)
+}
+
+func queryTypes(ctx context.Context, db *sql.DB, names []string, limit int) ([]FindTypeResult, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, len(names))
+	for i, n := range names {
+		args[i] = n
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT t.name, t.kind, f.project, f.path, t.line
+		FROM types t JOIN files f ON f.id = t.file_id
+		WHERE t.name IN (?`+strings.Repeat(",?", len(names)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []FindTypeResult
+	for rows.Next() {
+		var r FindTypeResult
+		if err := rows.Scan(&r.Name, &r.Kind, &r.Project, &r.Path, &r.Line); err != nil {
+			return nil, err
+		}
+		r.Path = offlineIndexPath(r.Project, r.Path)
+		results = append(results, r)
+	}
+	isHeader := func(p string) bool {
+		switch strings.ToLower(path.Ext(p)) {
+		case ".h", ".hpp", ".hxx":
+			return true
+		}
+		return false
+	}
+	sort.SliceStable(results, func(i, j int) bool { return isHeader(results[i].Path) && !isHeader(results[j].Path) })
+	return results[:min(limit, len(results))], rows.Err()
+}
+
+// offlineFindMember finds members by exact name.
+func offlineFindMember(ctx context.Context, db *sql.DB, name string, limit int) ([]FindMemberResult, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.name, coalesce(t.name, ''), m.member_kind, f.project, f.path, m.line
+		FROM members m JOIN files f ON f.id = m.file_id LEFT JOIN types t ON t.id = m.type_id
+		WHERE m.name = ? LIMIT ?`, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []FindMemberResult
+	for rows.Next() {
+		var r FindMemberResult
+		var project string
+		if err := rows.Scan(&r.Name, &r.OwnerName, &r.Kind, &project, &r.Path, &r.Line); err != nil {
+			return nil, err
+		}
+		r.Path = offlineIndexPath(project, r.Path)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// offlineFindFile finds files whose name, without extension, contains name's:
+// exact names first, then prefixes, then the rest, shorter paths first.
+func offlineFindFile(ctx context.Context, db *sql.DB, name string, limit int) ([]FindFileResult, error) {
+	stem := strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(stem)
+	rows, err := db.QueryContext(ctx, `SELECT project, path FROM files WHERE lower(path) LIKE ? ESCAPE '\'`, "%"+escaped+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	type hit struct {
+		file string
+		rank int
+	}
+	var hits []hit
+	for rows.Next() {
+		var project, p string
+		if err := rows.Scan(&project, &p); err != nil {
+			return nil, err
+		}
+		base := strings.ToLower(path.Base(slashPath(p)))
+		base = strings.TrimSuffix(base, path.Ext(base))
+		rank := 2
+		switch {
+		case base == stem:
+			rank = 0
+		case strings.HasPrefix(base, stem):
+			rank = 1
+		case !strings.Contains(base, stem):
+			continue
+		}
+		hits = append(hits, hit{offlineIndexPath(project, p), rank})
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].rank != hits[j].rank {
+			return hits[i].rank < hits[j].rank
+		}
+		return len(hits[i].file) < len(hits[j].file)
+	})
+	results := make([]FindFileResult, 0, min(limit, len(hits)))
+	for _, h := range hits[:min(limit, len(hits))] {
+		results = append(results, FindFileResult{File: h.file})
+	}
+	return results, rows.Err()
+}
+
+// offlineIndexPath turns a stored path into the "Project/relative" form the
+// service answers with, relative to the configured root it lies under, so
+// hostPath maps it back as usual. Paths under no known root stay absolute.
+func offlineIndexPath(project, stored string) string {
+	for _, p := range indexProjects {
+		if p.Name != project {
+			continue
+		}
+		for _, base := range append(append([]string{}, p.IndexedAs...), p.Paths...) {
+			if rel, ok := relUnder(base, stored); ok && rel != "" {
+				return project + "/" + rel
+			}
+		}
+	}
+	return slashPath(stored)
+}
+
+// offlineNote tells that the named workspaces were answered from their
+// databases, or is empty when none were.
+func offlineNote(labels []string) string {
+	switch len(labels) {
+	case 0:
+		return ""
+	case 1:
+		return "workspace " + labels[0] + " " + offlineLabel + ": its service is down, answered from its database"
+	}
+	return "workspaces " + strings.Join(labels, ", ") + " " + offlineLabel + ": their services are down, answered from their databases"
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// offlineFixture writes a workspace database for a Game project stored
+// under root and routes a service that isn't running to it.
+func offlineFixture(t *testing.T) (root, svcURL string) {
+	t.Helper()
+	state, projects, routes := breakerStatePath, indexProjects, workspaceRoutes
+	t.Cleanup(func() { breakerStatePath, indexProjects, workspaceRoutes = state, projects, routes })
+	breakerStatePath = filepath.Join(t.TempDir(), "breaker.json")
+
+	dir := t.TempDir()
+	root = filepath.ToSlash(filepath.Join(dir, "Game"))
+	file := filepath.Join(dir, "index.db")
+	db, err := sql.Open("sqlite", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		`CREATE TABLE files (id INTEGER PRIMARY KEY, path TEXT UNIQUE NOT NULL, project TEXT NOT NULL, module TEXT NOT NULL, language TEXT NOT NULL, mtime INTEGER NOT NULL)`,
+		`CREATE TABLE types (id INTEGER PRIMARY KEY, file_id INTEGER NOT NULL, name TEXT NOT NULL, kind TEXT NOT NULL, parent TEXT, line INTEGER NOT NULL)`,
+		`CREATE TABLE members (id INTEGER PRIMARY KEY, type_id INTEGER, file_id INTEGER NOT NULL, name TEXT NOT NULL, member_kind TEXT NOT NULL, line INTEGER NOT NULL)`,
+		`INSERT INTO files VALUES (1, '` + root + `/Source/Aim/AimComponent.cpp', 'Game', 'Game.Aim', 'cpp', 0), (2, '` + root + `/Source/Aim/AimComponent.h', 'Game', 'Game.Aim', 'cpp', 0), (3, '` + root + `/Source/Aim/AimComponentTypes.h', 'Game', 'Game.Aim', 'cpp', 0)`,
+		`INSERT INTO types VALUES (1, 1, 'UAimComponent', 'class', 'UActorComponent', 3), (2, 2, 'UAimComponent', 'class', 'UActorComponent', 12)`,
+		`INSERT INTO members VALUES (1, 2, 2, 'TickAim', 'function', 40), (2, NULL, 1, 'TickAim', 'function', 90)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svcURL = "http://" + ln.Addr().String()
+	ln.Close()
+	indexProjects = []indexProject{{Name: "Game", Paths: []string{root}}}
+	workspaceRoutes = []workspaceRoute{{Name: "game", URL: svcURL, Database: file}}
+	return root, svcURL
+}
+
+func TestOfflineLookups(t *testing.T) {
+	_, svcURL := offlineFixture(t)
+	targets := []workspaceRoute{workspaceRoutes[0]}
+	all := func(string) bool { return true }
+
+	// Headers first; "AimComponent" is found through its U prefix
+	got := smartRoute(context.Background(), targets, []symbolLookup{{"findTypeByName", "AimComponent"}}, all)
+	for _, want := range []string{
+		"Game/Source/Aim/AimComponent.h:12: class UAimComponent (Game)\nGame/Source/Aim/AimComponent.cpp:3: class UAimComponent (Game)",
+		"workspace game " + offlineLabel,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("find-type: missing %q in\n%s", want, got)
+		}
+	}
+
+	got = smartRoute(context.Background(), targets, []symbolLookup{{"findMember", "TickAim"}}, all)
+	for _, want := range []string{"Game/Source/Aim/AimComponent.h:40: function UAimComponent::TickAim", "AimComponent.cpp:90: function (global)::TickAim", offlineLabel} {
+		if !strings.Contains(got, want) {
+			t.Errorf("find-member: missing %q in\n%s", want, got)
+		}
+	}
+
+	results, offline, ok := indexLookup(context.Background(), svcURL, []batchQuery{
+		{Method: "findFileByName", Args: []interface{}{"AimComponent.h", map[string]interface{}{"maxResults": 5}}},
+		{Method: "listModules", Args: []interface{}{"Game"}},
+	})
+	if !ok || !offline {
+		t.Fatalf("ok=%v offline=%v, want an offline answer", ok, offline)
+	}
+	var files []string
+	for _, r := range batchResult[[]FindFileResult](results[0]) {
+		files = append(files, r.File)
+	}
+	// Exact names first, then longer names
+	if want := []string{"Game/Source/Aim/AimComponent.h", "Game/Source/Aim/AimComponent.cpp", "Game/Source/Aim/AimComponentTypes.h"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("find-file = %q, want %q", files, want)
+	}
+	if results[1] != nil {
+		t.Errorf("listModules answered offline: %s", results[1])
+	}
+}
+
+func TestOfflineFileListing(t *testing.T) {
+	root, _ := offlineFixture(t)
+	for _, name := range []string{"AimComponent.h", "AimComponentTypes.h"} {
+		p := filepath.Join(root, "Source", "Aim", name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, _, offline, err := indexedFilesUnder(root+"/Source", []string{"AimComponent"})
+	if err != nil || !offline {
+		t.Fatalf("err=%v offline=%v, want an offline listing", err, offline)
+	}
+	if len(files) != 3 {
+		t.Errorf("files = %q, want all three AimComponent files", files)
+	}
+	if got := globListing("**/AimComponent*.h", files[:1], false, false, true); !strings.Contains(got, offlineLabel) {
+		t.Errorf("offline Glob listing is not marked: %q", got)
+	}
+
+	// Without a database the lookup fails as before
+	workspaceRoutes[0].Database = ""
+	if _, _, _, err := indexedFilesUnder(root+"/Source", []string{"AimComponent"}); err == nil {
+		t.Error("listing succeeded with the service down and no database")
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ── Read: outline huge source files ──────────────────────────
+//
+// A full Read of a several-thousand-line engine header fills the context
+// with code the agent mostly doesn't need. When Read has no offset or limit
+// and the indexed source file is over the threshold, the proxy answers with
+// an outline of its types and members instead, with Read calls for each
+// type. Repeating the same Read returns the whole file.
+
+const (
+	defaultOutlineLines = 2000
+	maxOutlineMembers   = 300 // member lines listed across all types
+)
+
+// outlineLanguages are the languages the index parses types and members from.
+var outlineLanguages = map[string]bool{"cpp": true, "angelscript": true, "csharp": true}
+
+type outlineMember struct {
+	Name string `json:"name"`
+	Kind string `json:"member_kind"`
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+type outlineType struct {
+	FindTypeResult
+	End     int
+	Members []outlineMember
+}
+
+// outlineThreshold is the line count above which a full Read is outlined,
+// or 0 when outlining is off.
+func outlineThreshold() int {
+	switch n := hookConfig.OutlineLines; {
+	case n < 0:
+		return 0
+	case n == 0:
+		return defaultOutlineLines
+	default:
+		return n
+	}
+}
+
+// maybeOutline denies a full Read of a huge indexed source file with its
+// outline. It returns when the Read should go ahead.
+func maybeOutline(ti map[string]interface{}, p string) {
+	threshold := outlineThreshold()
+	if threshold == 0 || ti["offset"] != nil || ti["limit"] != nil {
+		return
+	}
+	if !outlineLanguages[extLanguages[strings.ToLower(filepath.Ext(p))]] {
+		return
+	}
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return
+	}
+	lines := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		lines++
+	}
+	if lines <= threshold || outlinedBefore(p) {
+		return
+	}
+
+	types := fileOutline(p, lines)
+	if len(types) == 0 {
+		return
+	}
+	rememberOutlined(p)
+	deny(formatOutline(p, lines, types))
+}
+
+// fileOutline fetches the types defined in p and their members, ordered by
+// line. Each type's End is its last member line, or the line before the
+// next type when it has none.
+func fileOutline(p string, lines int) []outlineType {
+	loc, ok := locateHostPath(path.Dir(slashPath(p)))
+	if !ok {
+		return nil
+	}
+	svcURL := resolveServiceURL(p)
+	q := url.Values{}
+	q.Set("module", loc.Module())
+	q.Set("project", loc.Project.Name)
+	q.Set("maxResults", "5000")
+	var data struct {
+		Types []FindTypeResult `json:"types"`
+		Error string           `json:"error"`
+	}
+	if !fetchJSON(svcURL+"/browse-module?"+q.Encode(), &data) || data.Error != "" {
+		return nil
+	}
+	var types []outlineType
+	for _, t := range data.Types {
+		if samePath(hostPath(t.Path), p) {
+			types = append(types, outlineType{FindTypeResult: t})
+		}
+	}
+	sort.SliceStable(types, func(i, j int) bool { return types[i].Line < types[j].Line })
+
+	// Members of every type, in as few /batch calls as the limit allows
+	queries := make([]batchQuery, len(types))
+	for i, t := range types {
+		opts := map[string]interface{}{"project": t.Project, "maxFunctions": 1000, "maxProperties": 1000}
+		queries[i] = batchQuery{Method: "listMembersForType", Args: []interface{}{t.Name, opts}}
+	}
+	results, _ := batchLookup(hookCtx, svcURL, queries)
+	for i, raw := range results {
+		r := batchResult[struct {
+			Functions  []outlineMember `json:"functions"`
+			Properties []outlineMember `json:"properties"`
+			EnumValues []outlineMember `json:"enumValues"`
+		}](raw)
+		// Same-named types elsewhere contribute members too
+		for _, list := range [][]outlineMember{r.Functions, r.Properties, r.EnumValues} {
+			for _, m := range list {
+				if samePath(hostPath(m.Path), p) {
+					types[i].Members = append(types[i].Members, m)
+				}
+			}
+		}
+		sort.SliceStable(types[i].Members, func(a, b int) bool { return types[i].Members[a].Line < types[i].Members[b].Line })
+	}
+
+	for i := range types {
+		t := &types[i]
+		switch {
+		case len(t.Members) > 0:
+			t.End = max(t.Line, t.Members[len(t.Members)-1].Line)
+		case i+1 < len(types):
+			t.End = max(t.Line, types[i+1].Line-1)
+		default:
+			t.End = lines
+		}
+	}
+	return types
+}
+
+// samePath compares host paths the way the index does.
+func samePath(a, b string) bool {
+	return a != "" && normalizePath(a) == normalizePath(b)
+}
+
+func formatOutline(p string, lines int, types []outlineType) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[unreal-index] %s has %d lines, so here is its outline from the index instead of the full file.\n"+
+		"Read a type with the offset and limit shown, or repeat the same Read to get the whole file.\n\n",
+		p, lines)
+
+	listed := 0
+	omitted := 0
+	for _, t := range types {
+		// A few lines of slack for the closing brace after the last member
+		limit := min(t.End+5, lines) - t.Line + 1
+		fmt.Fprintf(&b, "%s %s, lines %d-%d (Read offset=%d limit=%d)\n", t.Kind, t.Name, t.Line, t.End, t.Line, limit)
+		for _, m := range t.Members {
+			if listed == maxOutlineMembers {
+				omitted++
+				continue
+			}
+			fmt.Fprintf(&b, "  %d: %s %s\n", m.Line, m.Kind, m.Name)
+			listed++
+		}
+	}
+	if omitted > 0 {
+		fmt.Fprintf(&b, "\n(%d more members not listed)\n", omitted)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// ── Outlined-file memory ─────────────────────────────────────
+//
+// Hook calls are separate processes, so the files outlined in a session are
+// remembered in a small file in the temp directory. Nothing marks the end of
+// a session, so SessionStart prunes the files of sessions idle for a day.
+
+// outlinedStateMaxAge is how long a session's file outlives its last outline.
+const outlinedStateMaxAge = 24 * time.Hour
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+const outlinedStatePrefix = "unreal-index-outlined-"
+
+func outlinedStatePath() string {
+	session := unsafeFileChars.ReplaceAllString(hookSession, "_")
+	if session == "" {
+		session = "default"
+	}
+	return filepath.Join(os.TempDir(), outlinedStatePrefix+session+".txt")
+}
+
+// outlinedBefore reports whether p was already outlined in this session.
+func outlinedBefore(p string) bool {
+	data, err := os.ReadFile(outlinedStatePath())
+	if err != nil {
+		return false
+	}
+	key := normalizePath(p)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == key {
+			return true
+		}
+	}
+	return false
+}
+
+func rememberOutlined(p string) {
+	f, err := os.OpenFile(outlinedStatePath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, normalizePath(p))
+}
+
+// pruneOutlinedStates removes the outlined-file memory of sessions that
+// haven't outlined anything since before cutoff.
+func pruneOutlinedStates(cutoff time.Time) {
+	matches, _ := filepath.Glob(filepath.Join(os.TempDir(), outlinedStatePrefix+"*.txt"))
+	for _, p := range matches {
+		if info, err := os.Stat(p); err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(p)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutlineThreshold(t *testing.T) {
+	defer func(c hookSettings) { hookConfig = c }(hookConfig)
+	for _, tc := range []struct{ setting, want int }{{0, defaultOutlineLines}, {500, 500}, {-1, 0}} {
+		hookConfig.OutlineLines = tc.setting
+		if got := outlineThreshold(); got != tc.want {
+			t.Errorf("readOutlineLines %d: threshold %d, want %d", tc.setting, got, tc.want)
+		}
+	}
+}
+
+func TestFormatOutline(t *testing.T) {
+	types := []outlineType{
+		{FindTypeResult: FindTypeResult{Name: "EAimMode", Kind: "enum", Line: 10}, End: 19},
+		{
+			FindTypeResult: FindTypeResult{Name: "UAimComponent", Kind: "class", Line: 20},
+			End:            600,
+			Members:        []outlineMember{{Name: "TickAim", Kind: "function", Line: 45}, {Name: "Spread", Kind: "property", Line: 600}},
+		},
+	}
+	got := formatOutline("/work/Game/AimComponent.h", 602, types)
+	for _, want := range []string{
+		"/work/Game/AimComponent.h has 602 lines",
+		"enum EAimMode, lines 10-19 (Read offset=10 limit=15)",
+		"class UAimComponent, lines 20-600 (Read offset=20 limit=583)\n  45: function TickAim\n  600: property Spread",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+}
+
+func TestOutlinedBefore(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	defer func(s string) { hookSession = s }(hookSession)
+	hookSession = "a1b2/../c3"
+
+	if outlinedBefore("/work/Game/AimComponent.h") {
+		t.Fatal("outlined before anything was remembered")
+	}
+	rememberOutlined("/work/Game/AimComponent.h")
+	if !outlinedBefore("/work/Game/AimComponent.h") {
+		t.Error("remembered file not reported as outlined")
+	}
+	if outlinedBefore("/work/Game/Other.h") {
+		t.Error("other file reported as outlined")
+	}
+	hookSession = "another"
+	if outlinedBefore("/work/Game/AimComponent.h") {
+		t.Error("outline remembered across sessions")
+	}
+}
+
+func TestPruneOutlinedStates(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	defer func(s string) { hookSession = s }(hookSession)
+
+	hookSession = "idle"
+	rememberOutlined("/work/Game/AimComponent.h")
+	old := time.Now().Add(-outlinedStateMaxAge - time.Hour)
+	if err := os.Chtimes(outlinedStatePath(), old, old); err != nil {
+		t.Fatal(err)
+	}
+	hookSession = "active"
+	rememberOutlined("/work/Game/AimComponent.h")
+	unrelated := filepath.Join(os.TempDir(), "other.txt")
+	if err := os.WriteFile(unrelated, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(unrelated, old, old)
+
+	pruneOutlinedStates(time.Now().Add(-outlinedStateMaxAge))
+	if !outlinedBefore("/work/Game/AimComponent.h") {
+		t.Error("active session's outlines pruned")
+	}
+	hookSession = "idle"
+	if _, err := os.Stat(outlinedStatePath()); !os.IsNotExist(err) {
+		t.Errorf("idle session's outlines kept: %v", err)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("unrelated temp file removed: %v", err)
+	}
+}
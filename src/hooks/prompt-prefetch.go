@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ── UserPromptSubmit: prefetch named identifiers ─────────────
+//
+// Prompts often name the code they are about ("why does
+// UAimComponent::TickAim jitter"), and the agent would spend its first tool
+// calls finding those definitions. Before the prompt reaches the model, the
+// proxy looks the names up in one /batch call and injects where they are
+// defined. Prompt submission waits on the hook, so the lookup gets a short
+// budget and is dropped entirely when the service doesn't answer in time.
+
+const (
+	prefetchBudget  = 400 * time.Millisecond
+	maxPrefetchHits = 3 // definitions listed per identifier
+)
+
+var (
+	// Type::Member, with or without a UE prefix on the type
+	qualifiedIdentRe = regexp.MustCompile(`\b([A-Z]\w*)::(~?[A-Za-z_]\w*)`)
+	// UE-prefixed type names, as in uePrefixRe
+	ueIdentRe = regexp.MustCompile(`\b[UAFES][A-Z][a-zA-Z0-9_]+\b`)
+)
+
+// promptIdent is an identifier mentioned in a prompt. Member is empty for a
+// bare type name.
+type promptIdent struct {
+	Type, Member string
+}
+
+func (id promptIdent) String() string {
+	if id.Member == "" {
+		return id.Type
+	}
+	return id.Type + "::" + id.Member
+}
+
+// promptIdentifiers extracts Type::Member references and UE-prefixed type
+// names, in order of appearance. All-caps words (USB, FAQ, EOF) look like
+// prefixed names but aren't, so a name needs a lowercase letter.
+func promptIdentifiers(prompt string) []promptIdent {
+	seen := map[promptIdent]bool{}
+	var idents []promptIdent
+	add := func(id promptIdent) {
+		if !seen[id] {
+			seen[id] = true
+			idents = append(idents, id)
+		}
+	}
+	for _, m := range qualifiedIdentRe.FindAllStringSubmatch(prompt, -1) {
+		add(promptIdent{Type: m[1], Member: m[2]})
+	}
+	for _, name := range ueIdentRe.FindAllString(prompt, -1) {
+		if strings.ToUpper(name) != name {
+			add(promptIdent{Type: name})
+		}
+	}
+	return idents
+}
+
+// prefetchQueries builds the /batch queries for idents, one per identifier,
+// as many as fit in one request. A qualified name looks up the member on its type.
+func prefetchQueries(idents []promptIdent) ([]batchQuery, []promptIdent) {
+	var queries []batchQuery
+	var asked []promptIdent
+	for _, id := range idents {
+		if len(queries) == maxBatchQueries {
+			break
+		}
+		q := batchQuery{Method: "findTypeByName", Args: []interface{}{id.Type, map[string]interface{}{"maxResults": maxPrefetchHits, "includeAssets": false}}}
+		if id.Member != "" {
+			q = batchQuery{Method: "findMember", Args: []interface{}{id.Member, map[string]interface{}{"containingType": id.Type, "maxResults": maxPrefetchHits}}}
+		}
+		queries = append(queries, q)
+		asked = append(asked, id)
+	}
+	return queries, asked
+}
+
+// handleUserPromptSubmit injects definitions for identifiers named in the
+// prompt. Nothing is injected when none resolve or the service is slow.
+func handleUserPromptSubmit(prompt string) {
+	queries, asked := prefetchQueries(promptIdentifiers(prompt))
+	if len(queries) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(hookCtx, prefetchBudget)
+	defer cancel()
+	results, ok := batchLookup(ctx, resolveServiceURL(hookCwd), queries)
+	if !ok {
+		return
+	}
+
+	var lines []string
+	for i, raw := range results {
+		id := asked[i]
+		if id.Member == "" {
+			for _, h := range batchResult[[]FindTypeResult](raw) {
+				lines = append(lines, fmt.Sprintf("- %s: %s %s at %s", id, h.Kind, h.Name, prefetchLocation(h.Path, h.Line)))
+			}
+		} else {
+			for _, h := range batchResult[[]FindMemberResult](raw) {
+				lines = append(lines, fmt.Sprintf("- %s: %s %s::%s at %s", id, h.Kind, h.OwnerName, h.Name, prefetchLocation(h.Path, h.Line)))
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	addContext("UserPromptSubmit", "[unreal-index] Definitions of identifiers in this prompt (from the index; Read the lines before relying on them):\n"+
+		strings.Join(lines, "\n"))
+}
+
+// prefetchLocation is path:line with the index path mapped to the host, so
+// the agent can Read it directly.
+func prefetchLocation(indexPath string, line int) string {
+	if hp := hostPath(indexPath); hp != "" {
+		indexPath = hp
+	}
+	return fmt.Sprintf("%s:%d", indexPath, line)
+}
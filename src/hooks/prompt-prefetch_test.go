@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPromptIdentifiers(t *testing.T) {
+	got := promptIdentifiers("Why does UAimComponent::TickAim jitter? Compare with FAimSettings, USB and UAimComponent.")
+	want := []promptIdent{
+		{Type: "UAimComponent", Member: "TickAim"},
+		{Type: "UAimComponent"},
+		{Type: "FAimSettings"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("promptIdentifiers = %v, want %v", got, want)
+	}
+
+	if got := promptIdentifiers("fix the FAQ link and the EOF handling"); len(got) != 0 {
+		t.Errorf("all-caps words: got %v, want none", got)
+	}
+}
+
+func TestPrefetchQueries(t *testing.T) {
+	idents := []promptIdent{{Type: "UAimComponent", Member: "TickAim"}, {Type: "FAimSettings"}}
+	queries, asked := prefetchQueries(idents)
+	if len(queries) != 2 || queries[0].Method != "findMember" || queries[1].Method != "findTypeByName" {
+		t.Fatalf("queries = %+v", queries)
+	}
+	if opts := queries[0].Args[1].(map[string]interface{}); opts["containingType"] != "UAimComponent" {
+		t.Errorf("member query options = %v, want containingType UAimComponent", opts)
+	}
+	if !reflect.DeepEqual(asked, idents) {
+		t.Errorf("asked = %v, want %v", asked, idents)
+	}
+
+	many := make([]promptIdent, 15)
+	for i := range many {
+		many[i] = promptIdent{Type: "UType" + string(rune('a'+i))}
+	}
+	if queries, asked := prefetchQueries(many); len(queries) != maxBatchQueries || len(asked) != maxBatchQueries {
+		t.Errorf("got %d queries for 15 identifiers, want %d", len(queries), maxBatchQueries)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ── Read: correct guessed paths ──────────────────────────────
+//
+// Agents often Read a path they guessed from a type or module name, such as
+// Source/Game/Combat/AimComponent.h for a file that lives in a plugin. The
+// Read fails and a round of Glob and Grep follows. When the target is inside
+// the index but missing on disk, the proxy looks its name up instead and
+// denies with the real locations, most similar to the guess first.
+
+const maxReadCandidates = 10
+
+// handleRead outlines huge indexed files (see outline.go) and corrects
+// missing ones.
+func handleRead(ti map[string]interface{}) {
+	p := resolveHookPath(str(ti, "file_path"))
+	if !isAbsShellPath(p) || !isInsideIndex(p) {
+		allow()
+	}
+	st, err := os.Stat(p)
+	if err == nil && !st.IsDir() {
+		maybeOutline(ti, p)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		allow()
+	}
+
+	candidates := readCandidates(p)
+	if len(candidates) == 0 {
+		allow()
+	}
+	deny(fmt.Sprintf(
+		"[unreal-index] %s does not exist. The index has %s at:\n\n%s\n\n"+
+			"Read one of these paths instead.",
+		p, path.Base(slashPath(p)), strings.Join(candidates, "\n")))
+}
+
+// readCandidates returns the existing indexed files with the same name as
+// the missing file p, ranked by similarity to p.
+func readCandidates(p string) []string {
+	name := path.Base(slashPath(p))
+	q := url.Values{}
+	q.Set("filename", name)
+	q.Set("maxResults", "100")
+	var data FindFileResponse
+	if !fetchJSON(resolveServiceURL(p)+"/find-file?"+q.Encode(), &data) || data.Error != "" {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var candidates []string
+	for _, r := range data.Results {
+		hp := hostPath(r.File)
+		// /find-file matches on the name without extension, and by substring
+		if hp == "" || seen[hp] || !strings.EqualFold(path.Base(hp), name) {
+			continue
+		}
+		seen[hp] = true
+		if _, err := os.Stat(hp); err == nil {
+			candidates = append(candidates, hp)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return pathSimilarity(p, candidates[i]) > pathSimilarity(p, candidates[j])
+	})
+	if len(candidates) > maxReadCandidates {
+		candidates = candidates[:maxReadCandidates]
+	}
+	return candidates
+}
+
+// pathSimilarity scores how close candidate is to the guessed path: the
+// directories they end in count most, then directories of the guess found
+// anywhere in the candidate, then the directories they start with. Both
+// paths share the file name.
+func pathSimilarity(guess, candidate string) int {
+	g := strings.Split(strings.ToLower(path.Dir(slashPath(guess))), "/")
+	c := strings.Split(strings.ToLower(path.Dir(slashPath(candidate))), "/")
+
+	tail := 0
+	for tail < len(g) && tail < len(c) && g[len(g)-1-tail] == c[len(c)-1-tail] {
+		tail++
+	}
+	inCandidate := map[string]bool{}
+	for _, seg := range c {
+		inCandidate[seg] = true
+	}
+	shared := 0
+	for _, seg := range g {
+		if inCandidate[seg] {
+			shared++
+		}
+	}
+	lead := 0
+	for lead < len(g) && lead < len(c) && g[lead] == c[lead] {
+		lead++
+	}
+	return tail*10000 + shared*100 + lead
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestPathSimilarity(t *testing.T) {
+	guess := "/work/Game/Source/Game/Combat/AimComponent.h"
+	candidates := []string{
+		"/work/Game/Plugins/Weapons/Source/Weapons/Public/AimComponent.h",
+		"/work/Game/Plugins/Combat/Source/Combat/Public/AimComponent.h",
+		"/work/Game/Source/Game/Combat/Public/AimComponent.h",
+		"/work/Game/Plugins/Aim/Source/Aim/Combat/AimComponent.h",
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return pathSimilarity(guess, candidates[i]) > pathSimilarity(guess, candidates[j])
+	})
+	want := []string{
+		"/work/Game/Plugins/Aim/Source/Aim/Combat/AimComponent.h",         // same parent directory
+		"/work/Game/Source/Game/Combat/Public/AimComponent.h",             // every guessed directory
+		"/work/Game/Plugins/Combat/Source/Combat/Public/AimComponent.h",   // Combat
+		"/work/Game/Plugins/Weapons/Source/Weapons/Public/AimComponent.h", // only the shared root
+	}
+	for i := range want {
+		if candidates[i] != want[i] {
+			t.Errorf("rank %d = %s, want %s", i, candidates[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ── PostToolUse: push edits to the index ─────────────────────
+//
+// The watcher picks up edits on its own schedule, so a search right after an
+// Edit or Write could return the old text. After those tools run, the proxy
+// sends the file's new content to the workspace service that indexes it,
+// which parses and re-ingests just that file.
+
+// maxRefreshSize matches the largest file the service mirrors into Zoekt.
+const maxRefreshSize = 2000000
+
+type refreshFile struct {
+	Path    string `json:"path"`
+	Mtime   int64  `json:"mtime"`
+	Content string `json:"content"`
+}
+
+// handlePostToolUse refreshes the file an Edit, Write or MultiEdit changed.
+// Failures are ignored: the watcher still catches the edit later.
+func handlePostToolUse(toolName string, ti map[string]interface{}) {
+	switch toolName {
+	case "Edit", "Write", "MultiEdit":
+	default:
+		return
+	}
+	raw := str(ti, "file_path")
+	path := resolveHookPath(raw)
+	if !isAbsShellPath(path) || !isInsideIndex(path) {
+		return
+	}
+	if extLanguages[strings.ToLower(filepath.Ext(path))] == "" {
+		return
+	}
+	st, err := os.Stat(path)
+	if err != nil || st.IsDir() || st.Size() > maxRefreshSize {
+		return
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	// Send the path as the tool spelled it: the watcher stores the same
+	// spelling, and the service matches it case- and separator-insensitively.
+	// Under a symlinked root the service only knows the configured spelling.
+	if sp := serviceSpelling(path); sp != path || !isAbsShellPath(raw) {
+		raw = sp
+	}
+	body := map[string][]refreshFile{
+		"files": {{Path: raw, Mtime: st.ModTime().UnixMilli(), Content: string(content)}},
+	}
+	var resp struct {
+		Processed int `json:"processed"`
+	}
+	postJSON(resolveServiceURL(path)+"/internal/refresh-files", body, &resp)
+}
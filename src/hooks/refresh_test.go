@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandlePostToolUse(t *testing.T) {
+	defer func(p string, prefixes []string, projects []indexProject, def, cwd string) {
+		breakerStatePath, indexedPrefixes, indexProjects, configuredDefaultURL, hookCwd = p, prefixes, projects, def, cwd
+	}(breakerStatePath, indexedPrefixes, indexProjects, configuredDefaultURL, hookCwd)
+	breakerStatePath = filepath.Join(t.TempDir(), "breaker.json")
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.ToSlash(filepath.Join(dir, "Game"))
+	other := filepath.ToSlash(filepath.Join(dir, "Other"))
+	for _, name := range []string{root + "/Source/Aim.h", root + "/Source/Notes.bin", other + "/B.h"} {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte("class UAim {};\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	indexedPrefixes = []string{normalizePath(root)}
+	indexProjects = []indexProject{{Name: "Game", Paths: []string{root}}}
+
+	var sent []refreshFile
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Files []refreshFile `json:"files"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		sent = append(sent, body.Files...)
+		json.NewEncoder(w).Encode(map[string]int{"processed": len(body.Files)})
+	}))
+	defer svc.Close()
+	configuredDefaultURL = svc.URL
+	hookCwd = root
+
+	for _, tc := range []struct {
+		tool, path, want string
+	}{
+		{"Edit", root + "/Source/Aim.h", root + "/Source/Aim.h"},
+		// A relative path is sent resolved against the hook's cwd
+		{"Write", "Source/Aim.h", root + "/Source/Aim.h"},
+		{"Read", root + "/Source/Aim.h", ""},
+		{"Edit", root + "/Source/Notes.bin", ""},
+		{"Edit", other + "/B.h", ""},
+		{"Edit", root + "/Source/Missing.h", ""},
+	} {
+		sent = nil
+		handlePostToolUse(tc.tool, map[string]interface{}{"file_path": tc.path})
+		switch {
+		case tc.want == "" && len(sent) > 0:
+			t.Errorf("%s %s: refreshed %q, want nothing", tc.tool, tc.path, sent[0].Path)
+		case tc.want != "" && len(sent) != 1:
+			t.Errorf("%s %s: refreshed %d files, want 1", tc.tool, tc.path, len(sent))
+		case tc.want != "" && (sent[0].Path != tc.want || sent[0].Content != "class UAim {};\n" || sent[0].Mtime == 0):
+			t.Errorf("%s %s: refreshed %+v, want %q with its content and mtime", tc.tool, tc.path, sent[0], tc.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ── ripgrep-compatible output ────────────────────────────────
+//
+// With outputStyle "ripgrep", intercepted results are printed the way
+// `rg --no-heading` prints them, so the model sees the same text it would
+// get from the native tool: absolute paths, "path:line:text" for matches,
+// "path-line-text" for context, and "--" between non-contiguous groups.
+
+// absResultPath maps an index path to the absolute host path, keeping the
+// index path when no project mapping is configured.
+func absResultPath(indexPath string) string {
+	if hp := hostPath(indexPath); hp != "" {
+		return hp
+	}
+	return indexPath
+}
+
+// formatRipgrep prints grep hits (fetched with rawLines=true) in ripgrep's
+// content format. Files keep their first-hit order; lines within a file are
+// printed in order with overlapping context merged.
+func formatRipgrep(results []GrepResult, before, after int, withName, withLine bool) string {
+	type printed struct {
+		text  string
+		match bool
+	}
+	var order []string
+	byFile := map[string]map[int]printed{}
+	for _, r := range results {
+		f := absResultPath(r.File)
+		lines := byFile[f]
+		if lines == nil {
+			lines = map[int]printed{}
+			byFile[f] = lines
+			order = append(order, f)
+		}
+		lines[r.Line] = printed{r.Match, true}
+		b := r.Before[max(len(r.Before)-before, 0):]
+		for i, text := range b {
+			if n := r.Line - len(b) + i; !lines[n].match {
+				lines[n] = printed{text, false}
+			}
+		}
+		for i, text := range r.After[:min(after, len(r.After))] {
+			if n := r.Line + 1 + i; !lines[n].match {
+				lines[n] = printed{text, false}
+			}
+		}
+	}
+
+	var out []string
+	context := before > 0 || after > 0
+	for _, f := range order {
+		nums := make([]int, 0, len(byFile[f]))
+		for n := range byFile[f] {
+			nums = append(nums, n)
+		}
+		sort.Ints(nums)
+		for i, n := range nums {
+			if context && len(out) > 0 && (i == 0 || n != nums[i-1]+1) {
+				out = append(out, "--")
+			}
+			l := byFile[f][n]
+			sep := "-"
+			if l.match {
+				sep = ":"
+			}
+			var b strings.Builder
+			if withName {
+				b.WriteString(f + sep)
+			}
+			if withLine {
+				fmt.Fprintf(&b, "%d%s", n, sep)
+			}
+			b.WriteString(l.text)
+			out = append(out, b.String())
+		}
+	}
+	return strings.Join(out, "\n")
+}
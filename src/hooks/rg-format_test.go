@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatRipgrep(t *testing.T) {
+	results := []GrepResult{
+		{File: "/p/B.h", Line: 10, Match: "  Tick();", Before: []string{"a", "b"}, After: []string{"c", "d"}},
+		{File: "/p/A.cpp", Line: 3, Match: "x", Before: []string{"1", "2"}, After: []string{"4", "5"}},
+		{File: "/p/B.h", Line: 12, Match: "Tick2", Before: []string{"Tick();", "c"}, After: []string{"e"}},
+		{File: "/p/B.h", Line: 20, Match: "far", After: []string{"z"}},
+	}
+	got := formatRipgrep(results, 1, 1, true, true)
+	want := "/p/B.h-9-b\n/p/B.h:10:  Tick();\n/p/B.h-11-c\n/p/B.h:12:Tick2\n/p/B.h-13-e\n--\n" +
+		"/p/B.h:20:far\n/p/B.h-21-z\n--\n/p/A.cpp-2-2\n/p/A.cpp:3:x\n/p/A.cpp-4-4"
+	if got != want {
+		t.Errorf("formatRipgrep with context =\n%s\nwant\n%s", got, want)
+	}
+
+	if got, want := formatRipgrep(results[:2], 0, 0, true, false), "/p/B.h:  Tick();\n/p/A.cpp:x"; got != want {
+		t.Errorf("formatRipgrep without context = %q, want %q", got, want)
+	}
+}
+
+func TestRipgrepTruncated(t *testing.T) {
+	if got := ripgrepTruncated(); got != "" {
+		t.Errorf("nothing cut: %q, want none", got)
+	}
+	got := ripgrepTruncated("first 30 of 41 files", "the index stopped counting; totals are lower bounds")
+	if want := "\n[unreal-index] truncated: first 30 of 41 files; the index stopped counting; totals are lower bounds"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRipgrepFileListings(t *testing.T) {
+	defer func(style string) { hookConfig.OutputStyle = style }(hookConfig.OutputStyle)
+	hookConfig.OutputStyle = "ripgrep"
+
+	matches := []string{"/p/Game/Source/A.h", "/p/Game/Source/B.h"}
+	if got, want := globListing("**/*.h", matches, true, false, false), "/p/Game/Source/A.h\n/p/Game/Source/B.h"; got != want {
+		t.Errorf("globListing = %q, want %q", got, want)
+	}
+	if got := globListing("**/*.h", matches, false, true, false); got != "/p/Game/Source/A.h\n/p/Game/Source/B.h\n[unreal-index] truncated: the index listing was cut short" {
+		t.Errorf("partial globListing = %q", got)
+	}
+	if got := globListing("**/*.h", nil, false, false, false); got != "No files found" {
+		t.Errorf("empty globListing = %q, want Glob's", got)
+	}
+	if got, want := findListing("find Source -name *.h", []string{"Source/A.h"}, false, false), "Source/A.h"; got != want {
+		t.Errorf("findListing = %q, want %q", got, want)
+	}
+
+	hookConfig.OutputStyle = ""
+	if got := globListing("**/*.h", matches, false, false, false); !strings.HasPrefix(got, "[unreal-index] Glob intercepted") {
+		t.Errorf("index style lost its banner: %q", got)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveServiceURL(t *testing.T) {
+	defer func(routes []workspaceRoute, def string, cfg hookSettings) {
+		workspaceRoutes, configuredDefaultURL, hookConfig = routes, def, cfg
+	}(workspaceRoutes, configuredDefaultURL, hookConfig)
+
+	workspaceRoutes = []workspaceRoute{
+		{URL: "a", Prefixes: []string{"d:/game"}},
+		{URL: "b", Prefixes: []string{"d:/gameplugins"}},
+		{URL: "c", Prefixes: []string{"d:/game/plugins/combat"}},
+	}
+	configuredDefaultURL = "b"
+
+	tests := []struct {
+		path, want string
+	}{
+		{`D:\GamePlugins\Foo`, "b"},            // not a segment prefix of d:/game
+		{"d:/game/source", "a"},                // inside one workspace
+		{"d:/game/plugins/combat/public", "c"}, // nested: most specific wins
+		{"d:/game/plugins", "a"},               // above c, inside a
+		{"d:/", "b"},                           // ties go to the default
+		{"e:/other", "b"},                      // no match: default
+	}
+	for _, tt := range tests {
+		if got := resolveServiceURL(tt.path); got != tt.want {
+			t.Errorf("resolveServiceURL(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	hookConfig.RoutingTieBreak = "first"
+	if got := resolveServiceURL("d:/"); got != "a" {
+		t.Errorf("tie-break first: got %q, want a", got)
+	}
+}
+
+func TestIsInsideIndexSegments(t *testing.T) {
+	defer func(p []string) { indexedPrefixes = p }(indexedPrefixes)
+	indexedPrefixes = []string{"d:/game"}
+	for path, want := range map[string]bool{"d:/game/source": true, "d:/": true, "d:/gameplugins": false, "d:/gam": false} {
+		if got := isInsideIndex(path); got != want {
+			t.Errorf("isInsideIndex(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestSearchTargets(t *testing.T) {
+	defer func(routes []workspaceRoute, def string) {
+		workspaceRoutes, configuredDefaultURL = routes, def
+	}(workspaceRoutes, configuredDefaultURL)
+
+	workspaceRoutes = []workspaceRoute{
+		{Name: "game", URL: "a", Prefixes: []string{"d:/repo/game"}},
+		{Name: "engine", URL: "b", Prefixes: []string{"d:/repo/engine"}},
+	}
+	configuredDefaultURL = "a"
+
+	names := func(targets []workspaceRoute) (out []string) {
+		for _, ws := range targets {
+			out = append(out, ws.Name)
+		}
+		return out
+	}
+	for path, want := range map[string][]string{
+		"":                   {"game", "engine"},
+		"d:/repo":            {"game", "engine"},
+		"d:/repo/engine/src": {"engine"},
+		"e:/elsewhere":       {"game"},
+	} {
+		if got := names(searchTargets(path)); !reflect.DeepEqual(got, want) {
+			t.Errorf("searchTargets(%q) = %q, want %q", path, got, want)
+		}
+	}
+	if got := names(allTargets("d:/repo/engine")); !reflect.DeepEqual(got, []string{"engine", "game"}) {
+		t.Errorf("allTargets = %q", got)
+	}
+
+	merged := interleave([][]string{{"a", "b", "c"}, {"x", "b"}}, func(s string) string { return s })
+	if !reflect.DeepEqual(merged, []string{"a", "x", "b", "c"}) {
+		t.Errorf("interleave = %q", merged)
+	}
+}
+
+func TestResolveHookPath(t *testing.T) {
+	defer func(cwd string) { hookCwd = cwd }(hookCwd)
+
+	hookCwd = `D:\Game`
+	tests := []struct {
+		path, want string
+	}{
+		{"", ""},
+		{"Source/Combat", "D:/Game/Source/Combat"},
+		{".", "D:/Game"},
+		{"../Engine/Source", "D:/Engine/Source"},
+		{"/d/Engine", "/d/Engine"},
+		{`E:\Other`, `E:\Other`},
+	}
+	for _, tt := range tests {
+		if got := resolveHookPath(tt.path); got != tt.want {
+			t.Errorf("resolveHookPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	hookCwd = ""
+	if got := resolveHookPath("Source"); got != "Source" {
+		t.Errorf("unknown cwd: got %q, want Source", got)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ── SessionStart: live index status ──────────────────────────
+//
+// CLAUDE.local.md is written once at install time, so its workspace and port
+// details drift. At session start the proxy asks the workspace serving the
+// working directory for its current state and injects a short status block:
+// what is indexed, how fresh it is, whether full-text search is up, and how
+// to address the MCP tools.
+
+type healthStatus struct {
+	Zoekt *zoektStatus `json:"zoekt"`
+}
+
+type zoektStatus struct {
+	Available bool `json:"available"`
+	Indexing  bool `json:"indexing"`
+}
+
+type watcherStatus struct {
+	HasActiveWatcher bool   `json:"hasActiveWatcher"`
+	LastIngestAt     string `json:"lastIngestAt"`
+}
+
+type statsSummary struct {
+	Projects map[string]struct {
+		Files    int    `json:"files"`
+		Types    int    `json:"types"`
+		Language string `json:"language"`
+	} `json:"projects"`
+}
+
+// mcpTools are the unreal-index MCP tools, as listed in search-instructions.md.
+var mcpTools = []string{
+	"unreal_find_type", "unreal_find_children", "unreal_find_member", "unreal_grep",
+	"unreal_browse_module", "unreal_list_modules", "unreal_find_file", "unreal_find_asset",
+}
+
+// serviceState is what a workspace service reported; nil fields could not
+// be fetched.
+type serviceState struct {
+	Health  *healthStatus
+	Watcher *watcherStatus
+	Stats   *statsSummary
+}
+
+// handleSessionStart injects the status of the workspace serving the
+// working directory.
+func handleSessionStart() {
+	ws := routeFor(resolveServiceURL(hookCwd))
+	pruneOutlinedStates(time.Now().Add(-outlinedStateMaxAge))
+
+	var state serviceState
+	var wg sync.WaitGroup
+	for _, fetch := range []func(){
+		func() { state.Health = fetchInto[healthStatus](ws.URL + "/health") },
+		func() { state.Watcher = fetchInto[watcherStatus](ws.URL + "/watcher-status") },
+		func() { state.Stats = fetchInto[statsSummary](ws.URL + "/stats") },
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fetch()
+		}()
+	}
+	wg.Wait()
+
+	addContext("SessionStart", sessionStatus(ws, state, time.Now()))
+}
+
+// fetchInto decodes a GET response into a new T, or returns nil.
+func fetchInto[T any](u string) *T {
+	var v T
+	if !fetchJSON(u, &v) {
+		return nil
+	}
+	return &v
+}
+
+// sessionStatus formats the status block. It supersedes the install-time
+// routing notes in CLAUDE.local.md.
+func sessionStatus(ws workspaceRoute, state serviceState, now time.Time) string {
+	var b strings.Builder
+	b.WriteString("## unreal-index status (live at session start; supersedes workspace and port details in CLAUDE.local.md)\n\n")
+
+	fmt.Fprintf(&b, "- Workspace for this directory: %s (%s)\n", ws.label(), ws.URL)
+	var others []string
+	for _, o := range workspaceRoutes {
+		if o.URL != ws.URL {
+			others = append(others, fmt.Sprintf("%s (port %d)", o.label(), o.Port))
+		}
+	}
+	if len(others) > 0 {
+		fmt.Fprintf(&b, "- Other workspaces: %s\n", strings.Join(others, ", "))
+	}
+
+	if state.Health == nil {
+		b.WriteString("- Service: NOT RESPONDING. MCP tools for this workspace will fail and intercepted searches fall back to native tools. " +
+			"Check the dashboard at http://localhost:3846.\n")
+		return b.String()
+	}
+
+	if stats := state.Stats; stats != nil && len(stats.Projects) > 0 {
+		names := make([]string, 0, len(stats.Projects))
+		for name := range stats.Projects {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var projects []string
+		for _, name := range names {
+			p := stats.Projects[name]
+			projects = append(projects, fmt.Sprintf("%s (%s, %d files, %d types)", name, p.Language, p.Files, p.Types))
+		}
+		fmt.Fprintf(&b, "- Indexed projects: %s\n", strings.Join(projects, ", "))
+	}
+
+	switch watcher := state.Watcher; {
+	case watcher == nil:
+	case watcher.HasActiveWatcher:
+		fmt.Fprintf(&b, "- Freshness: file watcher active%s\n", ingestAge(watcher.LastIngestAt, now))
+	default:
+		fmt.Fprintf(&b, "- Freshness: NO ACTIVE FILE WATCHER%s. Results may miss recent edits; verify with Read before relying on them.\n", ingestAge(watcher.LastIngestAt, now))
+	}
+
+	switch z := state.Health.Zoekt; {
+	case z == nil:
+	case z.Available && z.Indexing:
+		b.WriteString("- Full-text search (Zoekt): up, reindexing\n")
+	case z.Available:
+		b.WriteString("- Full-text search (Zoekt): up\n")
+	default:
+		b.WriteString("- Full-text search (Zoekt): DOWN. unreal_grep and intercepted Grep are slower or unavailable.\n")
+	}
+
+	tools := make([]string, len(mcpTools))
+	for i, t := range mcpTools {
+		tools[i] = "mcp__unreal-index__" + t
+	}
+	fmt.Fprintf(&b, "- MCP tools (load via ToolSearch \"+unreal-index\"): %s\n", strings.Join(tools, ", "))
+	if len(workspaceRoutes) > 1 && ws.Name != "" {
+		fmt.Fprintf(&b, "- Pass workspace: \"%s\" to every unreal-index MCP tool call for code in this directory.\n", ws.Name)
+	}
+	return b.String()
+}
+
+// ingestAge describes how long ago the last ingest happened.
+func ingestAge(lastIngestAt string, now time.Time) string {
+	t, err := time.Parse(time.RFC3339, lastIngestAt)
+	if err != nil {
+		return ""
+	}
+	age := now.Sub(t)
+	switch {
+	case age < time.Minute:
+		return ", last ingest under a minute ago"
+	case age < time.Hour:
+		return fmt.Sprintf(", last ingest %d min ago", int(age.Minutes()))
+	case age < 48*time.Hour:
+		return fmt.Sprintf(", last ingest %d h ago", int(age.Hours()))
+	}
+	return fmt.Sprintf(", last ingest %d days ago", int(age.Hours()/24))
+}
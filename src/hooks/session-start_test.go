@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionStatus(t *testing.T) {
+	defer func(routes []workspaceRoute) { workspaceRoutes = routes }(workspaceRoutes)
+	workspaceRoutes = []workspaceRoute{
+		{Name: "game", Port: 3847, URL: "http://127.0.0.1:3847"},
+		{Name: "engine", Port: 3848, URL: "http://127.0.0.1:3848"},
+	}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	down := sessionStatus(workspaceRoutes[1], serviceState{}, now)
+	for _, want := range []string{"engine (http://127.0.0.1:3848)", "Other workspaces: game (port 3847)", "NOT RESPONDING"} {
+		if !strings.Contains(down, want) {
+			t.Errorf("service down: missing %q in\n%s", want, down)
+		}
+	}
+
+	state := serviceState{
+		Health:  &healthStatus{Zoekt: &zoektStatus{Available: true}},
+		Watcher: &watcherStatus{LastIngestAt: "2026-10-16T11:55:00.000Z"},
+		Stats:   &statsSummary{},
+	}
+	up := sessionStatus(workspaceRoutes[0], state, now)
+	for _, want := range []string{"NO ACTIVE FILE WATCHER, last ingest 5 min ago", "Zoekt): up\n", "mcp__unreal-index__unreal_find_type", `Pass workspace: "game"`} {
+		if !strings.Contains(up, want) {
+			t.Errorf("service up: missing %q in\n%s", want, up)
+		}
+	}
+}
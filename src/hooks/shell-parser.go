@@ -0,0 +1,557 @@
+package main
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// ── POSIX-ish shell parser ───────────────────────────────────
+//
+// Understands enough of sh/bash to split a Bash tool command into simple
+// commands: quoting, escapes, pipes, && / || / ; / &, subshells, { } groups,
+// env prefixes and redirections. Anything it cannot represent faithfully
+// (heredocs, unterminated quotes) is reported as an error so the caller can
+// let the command through untouched.
+
+var errShellUnsupported = errors.New("unsupported shell syntax")
+
+type shellTokenKind int
+
+const (
+	tokWord     shellTokenKind = iota
+	tokOp                      // && || ; | |& & ( ) newline
+	tokRedirect                // > >> < &> 2> 2>&1 ...
+)
+
+type shellToken struct {
+	Kind  shellTokenKind
+	Value string // unquoted word text, operator, or redirect operator
+	Raw   string // original source text of a word
+}
+
+// shellSimple is one simple command after env prefixes and redirections have been split off.
+type shellSimple struct {
+	Env  []string // NAME=value prefixes
+	Args []string // argv, Args[0] is the command name
+}
+
+// shellNode is either *shellSimple or *shellGroup.
+type shellNode interface{}
+
+// shellGroup is a parenthesised subshell or a { ...; } brace group.
+type shellGroup struct {
+	Body     *shellList
+	Subshell bool
+}
+
+// shellPipeline is a sequence of stages connected by | or |&.
+type shellPipeline struct {
+	Stages []shellNode
+}
+
+// shellList is a sequence of pipelines separated by && || ; & or newlines.
+// Ops[i] joins Items[i] and Items[i+1].
+type shellList struct {
+	Items []*shellPipeline
+	Ops   []string
+}
+
+// shellCommand is a simple command together with the context it runs in.
+type shellCommand struct {
+	Args  []string
+	Env   []string
+	Dir   string // working directory after any preceding cd, "" if unknown
+	Piped bool   // stdin comes from a previous pipeline stage
+	Pipes bool   // stdout feeds a later pipeline stage
+}
+
+// Name returns the command name with any directory part stripped (/usr/bin/grep → grep).
+func (c shellCommand) Name() string {
+	if len(c.Args) == 0 {
+		return ""
+	}
+	name := c.Args[0]
+	if idx := strings.LastIndexAny(name, "/\\"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return strings.TrimSuffix(strings.ToLower(name), ".exe")
+}
+
+// ── Tokenizer ────────────────────────────────────────────────
+
+func isShellBlank(c byte) bool { return c == ' ' || c == '\t' || c == '\r' }
+
+func isShellMeta(c byte) bool {
+	return isShellBlank(c) || c == '\n' || c == '|' || c == '&' || c == ';' || c == '(' || c == ')' || c == '<' || c == '>'
+}
+
+func tokenizeShell(src string) ([]shellToken, error) {
+	var toks []shellToken
+	i, n := 0, len(src)
+	for i < n {
+		c := src[i]
+		switch {
+		case isShellBlank(c):
+			i++
+			continue
+		case c == '\\' && i+1 < n && src[i+1] == '\n':
+			i += 2
+			continue
+		case c == '#' && (i == 0 || isShellMeta(src[i-1])):
+			for i < n && src[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '\n':
+			toks = append(toks, shellToken{Kind: tokOp, Value: ";"})
+			i++
+			continue
+		}
+
+		// Operators
+		if op := matchShellOp(src[i:]); op != "" {
+			toks = append(toks, shellToken{Kind: tokOp, Value: op})
+			i += len(op)
+			continue
+		}
+		if red := matchShellRedirect(src[i:]); red != "" {
+			if strings.HasPrefix(red, "<<") && red != "<<<" {
+				return nil, errShellUnsupported // heredoc body spans lines we don't track
+			}
+			toks = append(toks, shellToken{Kind: tokRedirect, Value: red})
+			i += len(red)
+			continue
+		}
+
+		// Word
+		start := i
+		var b strings.Builder
+		for i < n && !isShellMeta(src[i]) {
+			c := src[i]
+			switch c {
+			case '\\':
+				if i+1 >= n {
+					i++
+					continue
+				}
+				if src[i+1] != '\n' {
+					b.WriteByte(src[i+1])
+				}
+				i += 2
+			case '\'':
+				end := strings.IndexByte(src[i+1:], '\'')
+				if end < 0 {
+					return nil, errShellUnsupported
+				}
+				b.WriteString(src[i+1 : i+1+end])
+				i += end + 2
+			case '"':
+				j := i + 1
+				for j < n && src[j] != '"' {
+					if src[j] == '\\' && j+1 < n && strings.IndexByte("\"\\$`\n", src[j+1]) >= 0 {
+						if src[j+1] != '\n' {
+							b.WriteByte(src[j+1])
+						}
+						j += 2
+						continue
+					}
+					if src[j] == '$' && j+1 < n && src[j+1] == '(' {
+						end, err := skipShellParens(src, j+1)
+						if err != nil {
+							return nil, err
+						}
+						b.WriteString(src[j:end])
+						j = end
+						continue
+					}
+					b.WriteByte(src[j])
+					j++
+				}
+				if j >= n {
+					return nil, errShellUnsupported
+				}
+				i = j + 1
+			case '$':
+				if i+1 < n && src[i+1] == '(' {
+					end, err := skipShellParens(src, i+1)
+					if err != nil {
+						return nil, err
+					}
+					b.WriteString(src[i:end])
+					i = end
+				} else {
+					b.WriteByte(c)
+					i++
+				}
+			case '`':
+				end := strings.IndexByte(src[i+1:], '`')
+				if end < 0 {
+					return nil, errShellUnsupported
+				}
+				b.WriteString(src[i : i+end+2])
+				i += end + 2
+			default:
+				b.WriteByte(c)
+				i++
+			}
+		}
+		toks = append(toks, shellToken{Kind: tokWord, Value: b.String(), Raw: src[start:i]})
+	}
+	return toks, nil
+}
+
+func matchShellOp(s string) string {
+	for _, op := range []string{"&&", "||", "|&", ";;", "|", "&", ";", "(", ")"} {
+		if strings.HasPrefix(s, op) {
+			if op == "&" && strings.HasPrefix(s, "&>") {
+				return ""
+			}
+			return op
+		}
+	}
+	return ""
+}
+
+// matchShellRedirect recognises redirection operators, including an fd number
+// prefix (2>, 2>&1) and the &> / >& forms.
+func matchShellRedirect(s string) string {
+	j := 0
+	for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+		j++
+	}
+	rest := s[j:]
+	for _, op := range []string{"<<<", "<<-", "<<", ">>", ">&", "<&", "<>", ">|", ">", "<", "&>>", "&>"} {
+		if strings.HasPrefix(rest, op) {
+			if j > 0 && strings.HasPrefix(op, "&") {
+				return ""
+			}
+			return s[:j+len(op)]
+		}
+	}
+	return ""
+}
+
+// skipShellParens returns the index just past the ) matching the ( at src[open].
+func skipShellParens(src string, open int) (int, error) {
+	depth := 0
+	for i := open; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '\'':
+			end := strings.IndexByte(src[i+1:], '\'')
+			if end < 0 {
+				return 0, errShellUnsupported
+			}
+			i += end + 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, errShellUnsupported
+}
+
+// ── Parser ───────────────────────────────────────────────────
+
+type shellParser struct {
+	toks []shellToken
+	pos  int
+}
+
+// parseShell parses a command line into a list of pipelines.
+func parseShell(src string) (*shellList, error) {
+	toks, err := tokenizeShell(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &shellParser{toks: toks}
+	list, err := p.parseList("")
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, errShellUnsupported
+	}
+	return list, nil
+}
+
+func (p *shellParser) peek() *shellToken {
+	if p.pos < len(p.toks) {
+		return &p.toks[p.pos]
+	}
+	return nil
+}
+
+func (p *shellParser) isOp(v string) bool {
+	t := p.peek()
+	return t != nil && t.Kind == tokOp && t.Value == v
+}
+
+// isGroupClose reports whether the next token ends the enclosing group.
+func (p *shellParser) isGroupClose(closer string) bool {
+	t := p.peek()
+	if t == nil || closer == "" {
+		return false
+	}
+	if closer == ")" {
+		return t.Kind == tokOp && t.Value == ")"
+	}
+	return t.Kind == tokWord && t.Raw == "}"
+}
+
+func (p *shellParser) parseList(closer string) (*shellList, error) {
+	list := &shellList{}
+	for {
+		// Skip empty statements (leading/trailing separators)
+		for p.isOp(";") || p.isOp("&") {
+			p.pos++
+		}
+		if p.peek() == nil || p.isGroupClose(closer) {
+			break
+		}
+		pl, err := p.parsePipeline()
+		if err != nil {
+			return nil, err
+		}
+		if len(list.Items) > len(list.Ops) {
+			list.Ops = append(list.Ops, ";")
+		}
+		list.Items = append(list.Items, pl)
+
+		t := p.peek()
+		if t == nil || p.isGroupClose(closer) {
+			break
+		}
+		if t.Kind != tokOp {
+			return nil, errShellUnsupported
+		}
+		switch t.Value {
+		case "&&", "||", ";", "&":
+			p.pos++
+			list.Ops = append(list.Ops, t.Value)
+		default:
+			return nil, errShellUnsupported
+		}
+	}
+	if len(list.Ops) >= len(list.Items) && len(list.Ops) > 0 {
+		list.Ops = list.Ops[:len(list.Items)-1]
+	}
+	return list, nil
+}
+
+func (p *shellParser) parsePipeline() (*shellPipeline, error) {
+	pl := &shellPipeline{}
+	if t := p.peek(); t != nil && t.Kind == tokWord && t.Value == "!" {
+		p.pos++
+	}
+	for {
+		stage, err := p.parseStage()
+		if err != nil {
+			return nil, err
+		}
+		pl.Stages = append(pl.Stages, stage)
+		if p.isOp("|") || p.isOp("|&") {
+			p.pos++
+			continue
+		}
+		return pl, nil
+	}
+}
+
+func (p *shellParser) parseStage() (shellNode, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, errShellUnsupported
+	}
+	if t.Kind == tokOp && t.Value == "(" {
+		p.pos++
+		body, err := p.parseList(")")
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, errShellUnsupported
+		}
+		p.pos++
+		p.skipRedirects()
+		return &shellGroup{Body: body, Subshell: true}, nil
+	}
+	if t.Kind == tokWord && t.Raw == "{" {
+		p.pos++
+		body, err := p.parseList("}")
+		if err != nil {
+			return nil, err
+		}
+		if !p.isGroupClose("}") {
+			return nil, errShellUnsupported
+		}
+		p.pos++
+		p.skipRedirects()
+		return &shellGroup{Body: body}, nil
+	}
+	return p.parseSimple()
+}
+
+func (p *shellParser) skipRedirects() {
+	for {
+		t := p.peek()
+		if t == nil || t.Kind != tokRedirect {
+			return
+		}
+		p.pos++
+		if nt := p.peek(); nt != nil && nt.Kind == tokWord {
+			p.pos++
+		}
+	}
+}
+
+func (p *shellParser) parseSimple() (*shellSimple, error) {
+	cmd := &shellSimple{}
+	for {
+		t := p.peek()
+		if t == nil || t.Kind == tokOp {
+			break
+		}
+		if t.Kind == tokRedirect {
+			// Drop the redirect and its target (file name, or fd for 2>&1)
+			p.skipRedirects()
+			continue
+		}
+		p.pos++
+		if len(cmd.Args) == 0 && isShellAssignment(t.Raw) {
+			cmd.Env = append(cmd.Env, t.Value)
+			continue
+		}
+		cmd.Args = append(cmd.Args, t.Value)
+	}
+	if len(cmd.Args) == 0 && len(cmd.Env) == 0 {
+		return nil, errShellUnsupported
+	}
+	return cmd, nil
+}
+
+// isShellAssignment reports whether a raw word is a NAME=value prefix.
+func isShellAssignment(raw string) bool {
+	eq := strings.IndexByte(raw, '=')
+	if eq <= 0 {
+		return false
+	}
+	for i := 0; i < eq; i++ {
+		c := raw[i]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// ── Flattening ───────────────────────────────────────────────
+
+// shellWrappers are commands that run their arguments as another command.
+var shellWrappers = map[string]bool{
+	"time": true, "command": true, "builtin": true, "nohup": true, "exec": true, "env": true, "sudo": true,
+}
+
+// flattenShell walks the parsed command and returns every simple command in
+// execution order, tracking cd from the starting directory cwd for relative
+// paths and pipe position.
+func flattenShell(list *shellList, cwd string) []shellCommand {
+	var out []shellCommand
+	flattenList(list, cwd, &out)
+	return out
+}
+
+func flattenList(list *shellList, dir string, out *[]shellCommand) string {
+	for _, pl := range list.Items {
+		for i, stage := range pl.Stages {
+			piped := i > 0
+			pipes := i < len(pl.Stages)-1
+			switch s := stage.(type) {
+			case *shellGroup:
+				inner := flattenList(s.Body, dir, out)
+				if !s.Subshell && len(pl.Stages) == 1 {
+					dir = inner
+				}
+			case *shellSimple:
+				cmd := shellCommand{Args: unwrapShellCommand(s.Args), Env: s.Env, Dir: dir, Piped: piped, Pipes: pipes}
+				if len(cmd.Args) == 0 {
+					continue
+				}
+				if cmd.Name() == "cd" || cmd.Name() == "pushd" {
+					if len(pl.Stages) == 1 {
+						dir = shellChdir(dir, cmd.Args)
+					}
+					continue
+				}
+				*out = append(*out, cmd)
+			}
+		}
+	}
+	return dir
+}
+
+// unwrapShellCommand strips wrappers such as `time`, `env A=1` or `sudo` so the
+// real command name is in Args[0].
+func unwrapShellCommand(args []string) []string {
+	for len(args) > 0 {
+		name := strings.ToLower(args[0])
+		if !shellWrappers[name] {
+			return args
+		}
+		args = args[1:]
+		// command -v / -V only looks the name up
+		if name == "command" && len(args) > 0 && (args[0] == "-v" || args[0] == "-V") {
+			return nil
+		}
+		for len(args) > 0 && (strings.HasPrefix(args[0], "-") || name == "env" && isShellAssignment(args[0])) {
+			args = args[1:]
+		}
+	}
+	return args
+}
+
+// shellChdir applies a cd to the tracked directory. Unknown targets (cd -, cd ~,
+// variables) reset it to "" so later relative paths are treated as unresolved.
+func shellChdir(dir string, args []string) string {
+	if len(args) < 2 {
+		return ""
+	}
+	target := args[len(args)-1]
+	if target == "-" || strings.HasPrefix(target, "~") || strings.Contains(target, "$") {
+		return ""
+	}
+	return joinShellPath(dir, target)
+}
+
+// isAbsShellPath reports whether p is absolute on any host we run on:
+// /unix, /d/git-bash, d:/windows, d:\windows or \\unc.
+func isAbsShellPath(p string) bool {
+	if p == "" {
+		return false
+	}
+	if p[0] == '/' || p[0] == '\\' {
+		return true
+	}
+	return len(p) >= 3 && p[1] == ':' && (p[2] == '/' || p[2] == '\\')
+}
+
+// joinShellPath resolves p against dir. Returns "" when p is relative and dir is unknown.
+func joinShellPath(dir, p string) string {
+	if p == "" {
+		return ""
+	}
+	if isAbsShellPath(p) {
+		return p
+	}
+	if dir == "" {
+		return ""
+	}
+	joined := path.Clean(strings.ReplaceAll(dir, "\\", "/") + "/" + strings.ReplaceAll(p, "\\", "/"))
+	return joined
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlattenShell(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want []shellCommand
+	}{
+		{
+			cmd:  `grep -rn "Foo Bar" Source`,
+			want: []shellCommand{{Args: []string{"grep", "-rn", "Foo Bar", "Source"}}},
+		},
+		{
+			cmd: `cd D:/Game/Source && grep -rn Foo .`,
+			want: []shellCommand{
+				{Args: []string{"grep", "-rn", "Foo", "."}, Dir: "D:/Game/Source"},
+			},
+		},
+		{
+			cmd: `grep Foo x.h | head -5`,
+			want: []shellCommand{
+				{Args: []string{"grep", "Foo", "x.h"}, Pipes: true},
+				{Args: []string{"head", "-5"}, Piped: true},
+			},
+		},
+		{
+			cmd:  `(rg Foo)`,
+			want: []shellCommand{{Args: []string{"rg", "Foo"}}},
+		},
+		{
+			cmd:  `FOO=1 rg 'a|b' 2>/dev/null`,
+			want: []shellCommand{{Args: []string{"rg", "a|b"}, Env: []string{"FOO=1"}}},
+		},
+		{
+			cmd: `make || echo "failed; really" ; time find . -name '*.h'`,
+			want: []shellCommand{
+				{Args: []string{"make"}},
+				{Args: []string{"echo", "failed; really"}},
+				{Args: []string{"find", ".", "-name", "*.h"}},
+			},
+		},
+		{
+			cmd:  `git log --format="%H" -- src\ dir`,
+			want: []shellCommand{{Args: []string{"git", "log", "--format=%H", "--", "src dir"}}},
+		},
+		{
+			cmd:  `echo $(grep -l foo x) > out.txt`,
+			want: []shellCommand{{Args: []string{"echo", "$(grep -l foo x)"}}},
+		},
+	}
+	for _, tt := range tests {
+		list, err := parseShell(tt.cmd)
+		if err != nil {
+			t.Errorf("parseShell(%q) error: %v", tt.cmd, err)
+			continue
+		}
+		got := flattenShell(list, "")
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("flattenShell(%q)\n got  %#v\n want %#v", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestParseShellUnsupported(t *testing.T) {
+	for _, cmd := range []string{`echo "unterminated`, "cat <<EOF\nx\nEOF", `(grep foo`} {
+		if _, err := parseShell(cmd); err == nil {
+			t.Errorf("parseShell(%q) should fail", cmd)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+)
+
+// ── Task: index instructions for subagents ───────────────────
+//
+// Subagents don't see CLAUDE.local.md, so search-instructions.md asks the
+// model to paste the index requirement into every Task prompt. It often
+// forgets and the subagent falls back to native search. The proxy appends
+// the requirement itself, with the workspace that serves this directory.
+
+// searchInstructions is the CLAUDE.local.md section the installer writes;
+// the requirement subagents get is the one it quotes.
+//
+//go:embed search-instructions.md
+var searchInstructions string
+
+// subagentRequirement is the paragraph search-instructions.md asks to be
+// included verbatim in every Task prompt, and subagentRequirementLead its
+// first sentence. A prompt that already contains the lead carries the
+// requirement; a prompt that only mentions the MCP tools does not.
+var subagentRequirement, subagentRequirementLead = quotedRequirement(searchInstructions)
+
+// quotedRequirement returns the "> **MANDATORY REQUIREMENT" blockquote of
+// the instructions and its first sentence.
+func quotedRequirement(instructions string) (text, lead string) {
+	for _, line := range strings.Split(instructions, "\n") {
+		if text = strings.TrimPrefix(strings.TrimSpace(line), "> "); strings.HasPrefix(text, "**MANDATORY REQUIREMENT") {
+			if end := strings.Index(text, ". "); end >= 0 {
+				return text, text[:end+1]
+			}
+			return text, text
+		}
+	}
+	return "", ""
+}
+
+// handleTask appends the index requirement to a subagent prompt that
+// doesn't already carry it. The rewrite leaves the permission decision to
+// the user's settings.
+func handleTask(ti map[string]interface{}) {
+	prompt := str(ti, "prompt")
+	if prompt == "" || strings.Contains(prompt, subagentRequirementLead) {
+		allow()
+	}
+	updated := make(map[string]interface{}, len(ti))
+	for k, v := range ti {
+		updated[k] = v
+	}
+	updated["prompt"] = prompt + "\n\n" + subagentInstructions(routeFor(resolveServiceURL(hookCwd)))
+	rewriteInput(updated)
+}
+
+// subagentInstructions is the requirement plus the workspace to pass to the
+// MCP tools, as routed for the working directory.
+func subagentInstructions(ws workspaceRoute) string {
+	text := subagentRequirement
+	if ws.Name != "" {
+		text += fmt.Sprintf(" Pass `workspace: \"%s\"` to every unreal-index tool call.", ws.Name)
+	}
+	return text
+}
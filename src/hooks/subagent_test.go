@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSubagentInstructions(t *testing.T) {
+	if want := "**MANDATORY REQUIREMENT — DO NOT SKIP:** Before using Glob or Grep for ANY search, you MUST FIRST use the `mcp__unreal-index` MCP tools."; subagentRequirementLead != want {
+		t.Fatalf("requirement lead = %q, want %q", subagentRequirementLead, want)
+	}
+	if !strings.HasSuffix(subagentRequirement, "Glob/Grep are ONLY allowed as a last resort when unreal-index tools return no results.") {
+		t.Errorf("requirement = %q, want the whole quoted paragraph", subagentRequirement)
+	}
+	if got := subagentInstructions(workspaceRoute{URL: defaultServiceURL}); got != subagentRequirement {
+		t.Errorf("unnamed workspace: got %q, want the plain requirement", got)
+	}
+
+	got := subagentInstructions(workspaceRoute{Name: "game", Port: 3847, URL: "http://127.0.0.1:3847"})
+	if !strings.HasPrefix(got, subagentRequirement) || !strings.HasSuffix(got, "Pass `workspace: \"game\"` to every unreal-index tool call.") {
+		t.Errorf("named workspace: got %q", got)
+	}
+	// handleTask skips prompts that already carry the requirement
+	if !strings.Contains(got, subagentRequirementLead) {
+		t.Errorf("instructions lack the sentence handleTask checks for: %q", got)
+	}
+}
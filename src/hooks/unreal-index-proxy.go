@@ -1,14 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -16,31 +21,21 @@ import (
 const defaultServiceURL = "http://127.0.0.1:3847"
 const timeout = 5 * time.Second
 
+// grepLocalPages is how many pages of hits a Grep fetches when it filters or
+// merges results itself, so later pages slice the same hits.
+const grepLocalPages = 5
+
 // ── Regex patterns ───────────────────────────────────────────
 
 var (
 	fileExtRe = regexp.MustCompile(`(?i)\.(as|cpp|h|hpp|cs|py|ini|json|xml|yaml|yml|toml|md|txt)$`)
 
-	// Bash command detection
-	lsRe   = regexp.MustCompile(`^\s*(ls|dir|tree)\b`)
-	findRe = regexp.MustCompile(`^\s*find\b`)
-	grepRe = regexp.MustCompile(`^\s*(grep|rg)\b`)
-	catRe  = regexp.MustCompile(`^\s*(cat|head|tail)\b`)
-	wcRe   = regexp.MustCompile(`^\s*wc\b`)
-
-	// PowerShell commands (powershell -Command "..." or pwsh -c "...")
-	powershellRe     = regexp.MustCompile(`(?i)^\s*(powershell|pwsh)\b`)
-	getChildItemRe   = regexp.MustCompile(`(?i)Get-ChildItem|gci\b|ls\b`)
-	selectStringRe   = regexp.MustCompile(`(?i)Select-String|sls\b`)
-	getContentRe     = regexp.MustCompile(`(?i)Get-Content|gc\b|type\b`)
-	psFilterRe       = regexp.MustCompile(`(?i)-Filter\s+['"]?([^'"\s]+)['"]?`)
-	psPatternRe      = regexp.MustCompile(`(?i)-Pattern\s+['"]?([^'"\s]+)['"]?`)
-
-	// Extract -name argument from find commands
-	findNameRe = regexp.MustCompile(`-name\s+["']?([^"'\s]+)["']?`)
-
-	// Extract pattern from grep/rg commands — handles quoted patterns with \| and spaces
-	shellGrepPatternRe = regexp.MustCompile(`(?:grep|rg)\s+(?:-[a-zA-Z]+\s+(?:\d+\s+)?)*(?:"([^"]+)"|'([^']+)'|(\S+))`)
+	// PowerShell scripts (powershell -Command "..." or pwsh -c "...")
+	getChildItemRe = regexp.MustCompile(`(?i)Get-ChildItem|gci\b|ls\b`)
+	selectStringRe = regexp.MustCompile(`(?i)Select-String|sls\b`)
+	getContentRe   = regexp.MustCompile(`(?i)Get-Content|gc\b|type\b`)
+	psFilterRe     = regexp.MustCompile(`(?i)-Filter\s+['"]?([^'"\s]+)['"]?`)
+	psPatternRe    = regexp.MustCompile(`(?i)-Pattern\s+['"]?([^'"\s]+)['"]?`)
 
 	// Smart Grep routing: type definitions
 	classDefRe = regexp.MustCompile(`^(?:class|struct|enum)\s+(\w+)`)
@@ -53,15 +48,22 @@ var (
 // ── Types ────────────────────────────────────────────────────
 
 type HookInput struct {
-	ToolName  string                 `json:"tool_name"`
-	ToolInput map[string]interface{} `json:"tool_input"`
+	SessionID      string                 `json:"session_id"`
+	TranscriptPath string                 `json:"transcript_path"`
+	Cwd            string                 `json:"cwd"`
+	HookEventName  string                 `json:"hook_event_name"`
+	ToolName       string                 `json:"tool_name"`
+	ToolInput      map[string]interface{} `json:"tool_input"`
+	Prompt         string                 `json:"prompt"` // UserPromptSubmit
 }
 
 type HookOutput struct {
 	HSO struct {
-		Event    string `json:"hookEventName"`
-		Decision string `json:"permissionDecision"`
-		Reason   string `json:"permissionDecisionReason"`
+		Event    string                 `json:"hookEventName"`
+		Decision string                 `json:"permissionDecision,omitempty"`
+		Reason   string                 `json:"permissionDecisionReason,omitempty"`
+		Context  string                 `json:"additionalContext,omitempty"`
+		Input    map[string]interface{} `json:"updatedInput,omitempty"`
 	} `json:"hookSpecificOutput"`
 }
 
@@ -70,6 +72,11 @@ type GrepResult struct {
 	Line    int      `json:"line"`
 	Match   string   `json:"match"`
 	Context []string `json:"context"`
+	Before  []string `json:"before"` // with rawLines=true
+	After   []string `json:"after"`
+
+	Workspace string `json:"-"` // set when merging workspaces
+	Stale     bool   `json:"-"` // line text no longer found on disk
 }
 
 type GrepResponse struct {
@@ -77,6 +84,22 @@ type GrepResponse struct {
 	TotalMatches int          `json:"totalMatches"`
 	Truncated    bool         `json:"truncated"`
 	Error        string       `json:"error"`
+
+	Unavailable []string `json:"-"` // workspaces that failed, when merging
+}
+
+type GrepFileCount struct {
+	File  string `json:"file"`
+	Count int    `json:"count"`
+
+	Workspace string `json:"-"`
+}
+
+type GrepFilesResponse struct {
+	Files        []GrepFileCount `json:"files"`
+	TotalMatches int             `json:"totalMatches"`
+	Truncated    bool            `json:"truncated"`
+	Error        string          `json:"error"`
 }
 
 type FindFileResult struct {
@@ -88,12 +111,20 @@ type FindFileResponse struct {
 	Error   string           `json:"error"`
 }
 
+type BrowseModuleResponse struct {
+	Files     []string `json:"files"`
+	Truncated bool     `json:"truncated"`
+	Error     string   `json:"error"`
+}
+
 type FindTypeResult struct {
 	Name    string `json:"name"`
 	Kind    string `json:"kind"`
 	Project string `json:"project"`
 	Path    string `json:"path"`
 	Line    int    `json:"line"`
+
+	Workspace string `json:"-"`
 }
 
 type FindTypeResponse struct {
@@ -103,10 +134,12 @@ type FindTypeResponse struct {
 
 type FindMemberResult struct {
 	Name      string `json:"name"`
-	OwnerName string `json:"ownerName"`
-	Kind      string `json:"memberKind"`
+	OwnerName string `json:"type_name"`
+	Kind      string `json:"member_kind"`
 	Path      string `json:"path"`
 	Line      int    `json:"line"`
+
+	Workspace string `json:"-"`
 }
 
 type FindMemberResponse struct {
@@ -116,16 +149,44 @@ type FindMemberResponse struct {
 
 // ── Helpers ──────────────────────────────────────────────────
 
-func allow() { os.Exit(0) }
+func allow() {
+	exitMu.Lock()
+	os.Exit(0)
+}
+
+// respond writes the hook's answer and exits. exitMu keeps the budget
+// deadline from exiting halfway through the write.
+func respond(out HookOutput) {
+	data, _ := json.Marshal(out)
+	exitMu.Lock()
+	os.Stdout.Write(data)
+	os.Exit(0)
+}
 
 func deny(reason string) {
 	out := HookOutput{}
 	out.HSO.Event = "PreToolUse"
 	out.HSO.Decision = "deny"
 	out.HSO.Reason = reason
-	data, _ := json.Marshal(out)
-	os.Stdout.Write(data)
-	os.Exit(0)
+	respond(out)
+}
+
+// rewriteInput replaces the tool's input with input. It makes no permission
+// decision, so the user's permission rules still apply to the call.
+func rewriteInput(input map[string]interface{}) {
+	out := HookOutput{}
+	out.HSO.Event = "PreToolUse"
+	out.HSO.Input = input
+	respond(out)
+}
+
+// addContext injects text into the model's context for SessionStart and
+// UserPromptSubmit hooks.
+func addContext(event, text string) {
+	out := HookOutput{}
+	out.HSO.Event = event
+	out.HSO.Context = text
+	respond(out)
 }
 
 func str(m map[string]interface{}, k string) string {
@@ -155,37 +216,57 @@ func flagVal(m map[string]interface{}, k string) bool {
 	return false
 }
 
-func inferLang(glob, typ string) string {
-	src := glob
-	if src == "" {
-		src = typ
-	}
-	switch {
-	case strings.Contains(src, ".as") || typ == "as":
-		return "angelscript"
-	case strings.Contains(src, ".cpp") || strings.Contains(src, ".h") || strings.Contains(src, ".hpp") || typ == "cpp":
-		return "cpp"
-	case strings.Contains(src, ".ini") || strings.Contains(src, ".cfg"):
-		return "config"
+func fetchJSON(u string, target interface{}) bool {
+	return fetchJSONContext(hookCtx, u, target)
+}
+
+// fetchJSONContext is fetchJSON for a request that ctx can cancel early.
+func fetchJSONContext(ctx context.Context, u string, target interface{}) bool {
+	return sendJSON(ctx, http.MethodGet, u, nil, target)
+}
+
+// postJSON POSTs body as JSON and decodes the response into target.
+func postJSON(u string, body, target interface{}) bool {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return false
 	}
-	return ""
+	return sendJSON(hookCtx, http.MethodPost, u, data, target)
 }
 
-func fetchJSON(u string, target interface{}) bool {
+// sendJSON makes a request to a workspace service, unless its circuit
+// breaker is open, and decodes a 200 response into target.
+func sendJSON(ctx context.Context, method, u string, body []byte, target interface{}) bool {
+	if !breakerAllows(u) {
+		return false
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	client := &http.Client{Timeout: timeout}
-	resp, err := client.Get(u)
-	if err != nil || resp.StatusCode != 200 {
-		if resp != nil {
-			resp.Body.Close()
+	resp, err := client.Do(req)
+	if err != nil {
+		// A request its context ended (a caller's shorter budget, or the
+		// hook's deadline) says nothing about the service
+		if ctx.Err() == nil {
+			breakerFailure(u)
 		}
 		return false
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	breakerSuccess(u)
+	if resp.StatusCode != 200 {
+		return false
+	}
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return false
 	}
-	return json.Unmarshal(body, target) == nil
+	return json.Unmarshal(respBody, target) == nil
 }
 
 // ── Indexed path bypass + workspace routing ──────────────────
@@ -193,14 +274,39 @@ func fetchJSON(u string, target interface{}) bool {
 var indexedPrefixes []string
 
 type workspaceRoute struct {
-	Port     int      `json:"port"`
-	URL      string
-	Prefixes []string `json:"prefixes"`
+	Name      string `json:"name"`
+	Port      int    `json:"port"`
+	ZoektPort int    `json:"zoektPort"` // published Zoekt webserver, if any (see zoekt.go)
+	Database  string `json:"database"`  // host path of its SQLite database, if any (see offline.go)
+	URL       string
+	Prefixes  []string `json:"prefixes"`
 }
 
 var workspaceRoutes []workspaceRoute
 var configuredDefaultURL string // set from defaultPort in unreal-index-paths.json
 
+// hookSettings are the shared.hooks settings from workspaces.json.
+type hookSettings struct {
+	OutputStyle     string `json:"outputStyle"`      // "index" (default) or "ripgrep"
+	RoutingTieBreak string `json:"routingTieBreak"`  // "default" (default) or "first"
+	OutlineLines    int    `json:"readOutlineLines"` // outline full Reads of longer source files; 0 = default, <0 = never
+	LatencyBudgetMs int    `json:"latencyBudgetMs"`  // deadline for the whole hook call; 0 = default
+}
+
+var hookConfig hookSettings
+
+// hookCwd is the session's working directory from the hook payload. Relative
+// tool paths resolve against it and it picks the workspace when none is given.
+var hookCwd string
+
+// hookSession is the session ID from the hook payload, for state kept
+// between hook calls.
+var hookSession string
+
+// ripgrepOutput reports whether intercepted results should look exactly like
+// ripgrep's, with absolute host paths.
+func ripgrepOutput() bool { return hookConfig.OutputStyle == "ripgrep" }
+
 func init() {
 	exe, err := os.Executable()
 	if err != nil {
@@ -215,7 +321,11 @@ func init() {
 		IndexedPrefixes []string         `json:"indexedPrefixes"`
 		Workspaces      []workspaceRoute `json:"workspaces"`
 		DefaultPort     int              `json:"defaultPort"`
+		Projects        []indexProject   `json:"projects"`
+		Hooks           hookSettings     `json:"hooks"`
 	}
+	// install.js writes project paths with symlinks already resolved, and
+	// the configured spellings as well where they differ
 	if json.Unmarshal(data, &cfg) == nil {
 		for _, p := range cfg.IndexedPrefixes {
 			indexedPrefixes = append(indexedPrefixes, normalizePath(p))
@@ -226,28 +336,56 @@ func init() {
 				normalized = append(normalized, normalizePath(p))
 			}
 			workspaceRoutes = append(workspaceRoutes, workspaceRoute{
-				Port:     ws.Port,
-				URL:      fmt.Sprintf("http://127.0.0.1:%d", ws.Port),
-				Prefixes: normalized,
+				Name:      ws.Name,
+				Port:      ws.Port,
+				ZoektPort: ws.ZoektPort,
+				Database:  ws.Database,
+				URL:       fmt.Sprintf("http://127.0.0.1:%d", ws.Port),
+				Prefixes:  normalized,
 			})
 		}
 		if cfg.DefaultPort > 0 {
 			configuredDefaultURL = fmt.Sprintf("http://127.0.0.1:%d", cfg.DefaultPort)
 		}
+		indexProjects = append(indexProjects, cfg.Projects...)
+		hookConfig = cfg.Hooks
+	}
+}
+
+// resolveHookPath resolves a relative tool path against the session's working
+// directory and follows symlinks. Relative paths stay as they are when the
+// working directory is unknown.
+func resolveHookPath(p string) string {
+	if p == "" || hookCwd == "" && !isAbsShellPath(p) {
+		return p
+	}
+	if !isAbsShellPath(p) {
+		p = joinShellPath(slashPath(hookCwd), p)
 	}
+	return canonicalPath(p)
 }
 
-// resolveServiceURL returns the service URL for the workspace that matches the given path.
+// resolveServiceURL returns the service URL of the workspace whose prefix
+// matches the given path most specifically (see routeScore). Ties, such as a
+// drive root above several workspaces, go to the routingTieBreak setting.
 func resolveServiceURL(path string) string {
 	if len(workspaceRoutes) > 0 && path != "" {
 		norm := normalizePath(path)
-		for _, ws := range workspaceRoutes {
+		best, bestScore := -1, -1
+		for i, ws := range workspaceRoutes {
 			for _, prefix := range ws.Prefixes {
-				if strings.HasPrefix(norm, prefix) || strings.HasPrefix(prefix, norm) {
-					return ws.URL
+				n := routeScore(norm, prefix)
+				if n < 0 {
+					continue
+				}
+				if n > bestScore || n == bestScore && preferOnTie(ws, workspaceRoutes[best]) {
+					best, bestScore = i, n
 				}
 			}
 		}
+		if best >= 0 {
+			return workspaceRoutes[best].URL
+		}
 	}
 	// Fall back to configured default (from install), then first workspace, then hardcoded default
 	if configuredDefaultURL != "" {
@@ -259,16 +397,29 @@ func resolveServiceURL(path string) string {
 	return defaultServiceURL
 }
 
-// normalizePath lowercases, converts backslashes to forward slashes,
-// strips trailing slashes, and converts Git Bash /d/... to d:/...
-func normalizePath(p string) string {
-	s := strings.ToLower(strings.ReplaceAll(p, "\\", "/"))
-	s = strings.TrimRight(s, "/")
-	// Git Bash: /d/path → d:/path
-	if len(s) >= 3 && s[0] == '/' && s[2] == '/' && s[1] >= 'a' && s[1] <= 'z' {
-		s = string(s[1]) + ":" + s[2:]
+// routeScore ranks how well a normalized workspace prefix matches a
+// normalized path, comparing whole segments: a prefix containing the path
+// scores its length + 1 (most specific wins), a prefix lying below the path
+// scores 0 (all such prefixes tie), and no overlap is -1. install.js uses the
+// same ranking so the proxy and the installer pick the same workspace.
+func routeScore(path, prefix string) int {
+	switch {
+	case path == prefix || prefix == "" || strings.HasPrefix(path, prefix+"/"):
+		return len(prefix) + 1
+	case path == "" || strings.HasPrefix(prefix, path+"/"):
+		return 0
+	}
+	return -1
+}
+
+// preferOnTie reports whether candidate should replace current when both
+// match a path equally well. "default" (the default) prefers the workspace
+// that owns the installed project; "first" keeps configuration order.
+func preferOnTie(candidate, current workspaceRoute) bool {
+	if hookConfig.RoutingTieBreak == "first" {
+		return false
 	}
-	return s
+	return candidate.URL == configuredDefaultURL && current.URL != configuredDefaultURL
 }
 
 // isInsideIndex returns true if the path is empty, unresolvable, or overlaps
@@ -280,69 +431,107 @@ func isInsideIndex(path string) bool {
 	}
 	norm := normalizePath(path)
 	for _, prefix := range indexedPrefixes {
-		if strings.HasPrefix(norm, prefix) || strings.HasPrefix(prefix, norm) {
+		if routeScore(norm, prefix) >= 0 {
 			return true
 		}
 	}
 	return false
 }
 
-// extractShellTargetPath tries to extract the target directory from a shell command.
-func extractShellTargetPath(cmd string) string {
-	parts := strings.Fields(cmd)
-	if len(parts) < 2 {
+// ── Smart routing: find-type / find-member ──────────────────
+
+// symbolLookup is a smart-routing candidate: a findTypeByName or findMember
+// query for a name taken from the pattern.
+type symbolLookup struct {
+	Method, Name string
+}
+
+// smartRoute sends every candidate lookup to each target in one /batch
+// request and returns the formatted hits of the first candidate, in
+// priority order, that found anything in the files keep accepts (the Grep's
+// path, glob and type).
+func smartRoute(ctx context.Context, targets []workspaceRoute, lookups []symbolLookup, keep func(string) bool) string {
+	if len(lookups) == 0 {
 		return ""
 	}
-	// For grep/rg: last non-flag argument that looks like a path
-	if grepRe.MatchString(cmd) {
-		for i := len(parts) - 1; i >= 1; i-- {
-			arg := parts[i]
-			if strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "'") || strings.HasPrefix(arg, "\"") {
-				continue
-			}
-			if strings.ContainsAny(arg, "/\\") || (len(arg) >= 3 && arg[1] == ':') {
-				return arg
-			}
+	queries := make([]batchQuery, len(lookups))
+	for i, l := range lookups {
+		queries[i] = batchQuery{Method: l.Method, Args: []interface{}{l.Name, map[string]interface{}{"maxResults": 20}}}
+	}
+	type lookupResults struct {
+		results     []json.RawMessage
+		offline, ok bool
+	}
+	answers := fanOut(targets, func(ws workspaceRoute) lookupResults {
+		results, offline, ok := indexLookup(ctx, ws.URL, queries)
+		return lookupResults{results, offline, ok}
+	})
+	perWorkspace := make([][]json.RawMessage, len(targets))
+	var unavailable, offline []string
+	for w, a := range answers {
+		perWorkspace[w] = a.results
+		if !a.ok {
+			unavailable = append(unavailable, targets[w].label())
+		}
+		if a.offline {
+			offline = append(offline, targets[w].label())
 		}
 	}
-	// For find: first non-flag argument after "find"
-	if findRe.MatchString(cmd) {
-		for i := 1; i < len(parts); i++ {
-			if strings.HasPrefix(parts[i], "-") {
-				break
+	note := ""
+	if n := unavailableNote(unavailable); n != "" {
+		note = "\n\n[unreal-index] " + n + "."
+	}
+	if n := offlineNote(offline); n != "" {
+		note += "\n\n[unreal-index] " + n + "."
+	}
+
+	labeled := len(targets) > 1
+	for i, l := range lookups {
+		switch l.Method {
+		case "findTypeByName":
+			lists := make([][]FindTypeResult, len(targets))
+			for w, results := range perWorkspace {
+				for _, r := range batchResult[[]FindTypeResult](results[i]) {
+					if keep(r.Path) {
+						r.Workspace = targets[w].label()
+						lists[w] = append(lists[w], r)
+					}
+				}
 			}
-			if strings.ContainsAny(parts[i], "/\\") || parts[i] == "." || (len(parts[i]) >= 3 && parts[i][1] == ':') {
-				return parts[i]
+			if result := formatTypeHits(l.Name, lists, labeled); result != "" {
+				return result + note
 			}
-		}
-	}
-	// For ls: first non-flag argument
-	if lsRe.MatchString(cmd) {
-		for i := 1; i < len(parts); i++ {
-			if strings.HasPrefix(parts[i], "-") {
-				continue
+		case "findMember":
+			lists := make([][]FindMemberResult, len(targets))
+			for w, results := range perWorkspace {
+				for _, r := range batchResult[[]FindMemberResult](results[i]) {
+					if keep(r.Path) {
+						r.Workspace = targets[w].label()
+						lists[w] = append(lists[w], r)
+					}
+				}
+			}
+			if result := formatMemberHits(l.Name, lists, labeled); result != "" {
+				return result + note
 			}
-			return parts[i]
 		}
 	}
 	return ""
 }
 
-// ── Smart routing: try find-type ─────────────────────────────
-
-func tryFindType(svcURL, name string) string {
-	p := url.Values{}
-	p.Set("name", name)
-	p.Set("maxResults", "20")
-
-	var data FindTypeResponse
-	if !fetchJSON(svcURL+"/find-type?"+p.Encode(), &data) || data.Error != "" || len(data.Results) == 0 {
+func formatTypeHits(name string, lists [][]FindTypeResult, labeled bool) string {
+	results := interleave(lists, func(r FindTypeResult) string { return fmt.Sprintf("%s:%d", r.Path, r.Line) })
+	if len(results) == 0 {
 		return ""
 	}
 
 	var lines []string
-	for _, r := range data.Results {
-		lines = append(lines, fmt.Sprintf("%s:%d: %s %s (%s)", r.Path, r.Line, r.Kind, r.Name, r.Project))
+	for _, r := range results {
+		ln := fmt.Sprintf("%s:%d: %s %s (%s)", r.Path, r.Line, r.Kind, r.Name, r.Project)
+		if labeled {
+			ln = "[" + r.Workspace + "] " + ln
+		}
+		lines = append(lines, ln)
 	}
 	return fmt.Sprintf(
 		"[unreal-index] Smart-routed to /find-type for \"%s\":\n\n%s\n\n"+
@@ -350,25 +539,23 @@ func tryFindType(svcURL, name string) string {
 		name, strings.Join(lines, "\n"))
 }
 
-// ── Smart routing: try find-member ───────────────────────────
-
-func tryFindMember(svcURL, name string) string {
-	p := url.Values{}
-	p.Set("name", name)
-	p.Set("maxResults", "20")
-
-	var data FindMemberResponse
-	if !fetchJSON(svcURL+"/find-member?"+p.Encode(), &data) || data.Error != "" || len(data.Results) == 0 {
+func formatMemberHits(name string, lists [][]FindMemberResult, labeled bool) string {
+	results := interleave(lists, func(r FindMemberResult) string { return fmt.Sprintf("%s:%d", r.Path, r.Line) })
+	if len(results) == 0 {
 		return ""
 	}
 
 	var lines []string
-	for _, r := range data.Results {
+	for _, r := range results {
 		owner := r.OwnerName
 		if owner == "" {
 			owner = "(global)"
 		}
-		lines = append(lines, fmt.Sprintf("%s:%d: %s %s::%s", r.Path, r.Line, r.Kind, owner, r.Name))
+		ln := fmt.Sprintf("%s:%d: %s %s::%s", r.Path, r.Line, r.Kind, owner, r.Name)
+		if labeled {
+			ln = "[" + r.Workspace + "] " + ln
+		}
+		lines = append(lines, ln)
 	}
 	return fmt.Sprintf(
 		"[unreal-index] Smart-routed to /find-member for \"%s\":\n\n%s\n\n"+
@@ -380,8 +567,15 @@ func tryFindMember(svcURL, name string) string {
 
 func handleGrep(ti map[string]interface{}) {
 	pattern := str(ti, "pattern")
-	path := str(ti, "path")
+	// Grep searches the working directory when no path is given
+	path := resolveHookPath(str(ti, "path"))
+	if path == "" {
+		path = hookCwd
+	}
 	outputMode := str(ti, "output_mode")
+	if outputMode == "" {
+		outputMode = "files_with_matches"
+	}
 	glob := str(ti, "glob")
 	typ := str(ti, "type")
 
@@ -394,369 +588,1178 @@ func handleGrep(ti map[string]interface{}) {
 		allow()
 	}
 
-	svcURL := resolveServiceURL(path)
-
-	// Smart routing: detect type definition patterns
-	if m := classDefRe.FindStringSubmatch(pattern); m != nil {
-		if result := tryFindType(svcURL, m[1]); result != "" {
-			deny(result)
-		}
+	// Files edited moments ago may not be ingested yet: search the disk
+	if staleInScope(scopeDirs(path)) {
+		allow()
 	}
 
-	// Smart routing: detect UE-prefixed type names (UAimComponent, FVector, etc.)
-	if uePrefixRe.MatchString(pattern) {
-		if result := tryFindType(svcURL, pattern); result != "" {
-			deny(result)
-		}
+	// path, glob and type restrict the hits; the service only knows projects
+	// and languages, so filter here and over-fetch to still fill the page
+	filter := grepInvocation{Tool: "rg"}
+	if strings.HasPrefix(glob, "!") {
+		filter.Excludes = []string{glob[1:]}
+	} else if glob != "" {
+		filter.Includes = []string{glob}
 	}
-
-	// Smart routing: detect function definition patterns
-	if m := funcDefRe.FindStringSubmatch(pattern); m != nil {
-		if result := tryFindMember(svcURL, m[1]); result != "" {
-			deny(result)
-		}
+	if typ != "" {
+		filter.TypeExts = grepTypeExtsFor(typ)
 	}
+	scope := scopeDirs(path)
+	keep := func(file string) bool { return filter.Keep(file) && inScope(file, scope) }
 
-	// Fall through to regular grep
+	offset := int(num(ti, "offset"))
 	maxRes := int(num(ti, "head_limit"))
 	if maxRes == 0 {
 		maxRes = 30
 	}
 
+	targets := searchTargets(path)
+	filtered := filter.HasFilters() || len(scope) > 0
+	local := filtered || len(targets) > 1
+	fetch := maxRes
+	if local {
+		// Filtered and merged pages are sliced here, every page from the
+		// same fetch, so its size must not depend on the offset
+		fetch = maxRes * grepLocalPages
+	}
+
 	p := url.Values{}
 	p.Set("pattern", pattern)
-	p.Set("maxResults", fmt.Sprintf("%d", maxRes))
+	p.Set("maxResults", fmt.Sprintf("%d", fetch))
 	p.Set("grouped", "false")
 	p.Set("symbols", "false")
 	if flagVal(ti, "-i") {
 		p.Set("caseSensitive", "false")
 	}
-	ctx := num(ti, "-C")
+	ctx := int(num(ti, "-C"))
 	if ctx == 0 {
-		ctx = num(ti, "context")
+		ctx = int(num(ti, "context"))
+	}
+	before, after := ctx, ctx
+	if _, ok := ti["-B"]; ok {
+		before = int(num(ti, "-B"))
+	}
+	if _, ok := ti["-A"]; ok {
+		after = int(num(ti, "-A"))
+	}
+	if n := max(before, after); n > 0 {
+		p.Set("contextLines", fmt.Sprintf("%d", n))
 	}
-	if ctx > 0 {
-		p.Set("contextLines", fmt.Sprintf("%d", int(ctx)))
+	if ripgrepOutput() {
+		p.Set("rawLines", "true")
 	}
-	if lang := inferLang(glob, typ); lang != "" {
+	if lang := filter.Language(); lang != "" {
 		p.Set("language", lang)
 	}
-
-	var data GrepResponse
-	if !fetchJSON(svcURL+"/grep?"+p.Encode(), &data) || data.Error != "" || len(data.Results) == 0 {
-		allow()
+	if len(scope) == 1 {
+		if loc, ok := locateHostPath(scope[0]); ok {
+			p.Set("project", loc.Project.Name)
+			// A project lives in one workspace
+			for _, ws := range workspaceRoutes {
+				if ws.Port == loc.Project.Port {
+					targets = []workspaceRoute{ws}
+				}
+			}
+		}
 	}
+	if offset > 0 && !local {
+		p.Set("offset", fmt.Sprintf("%d", offset))
+	}
+
+	// The grep runs alongside the smart-routing lookups, so a miss doesn't
+	// wait for one round trip after the other. count / files_with_matches
+	// come from whole-corpus aggregation.
+	var (
+		files       []GrepFileCount
+		partial     bool
+		unavailable []string
+		data        GrepResponse
+		ok          bool
+	)
+	searched := make(chan struct{})
+	go func() {
+		defer close(searched)
+		if outputMode != "content" {
+			files, partial, unavailable, ok = grepByFile(targets, p, keep)
+		} else {
+			data, ok = grepAll(targets, p)
+		}
+	}()
+
+	// Smart routing only answers the first page; later pages continue a plain
+	// grep. Its type/member listings have no ripgrep equivalent.
+	if offset == 0 && !ripgrepOutput() {
+		symbolTargets := allTargets(path)
+
+		// Candidate lookups in priority order, sent together
+		var lookups []symbolLookup
 
-	mode := outputMode
-	if mode == "" {
-		mode = "files_with_matches"
+		// Smart routing: detect type definition patterns
+		if m := classDefRe.FindStringSubmatch(pattern); m != nil {
+			lookups = append(lookups, symbolLookup{"findTypeByName", m[1]})
+		}
+
+		// Smart routing: detect UE-prefixed type names (UAimComponent, FVector, etc.)
+		if uePrefixRe.MatchString(pattern) {
+			lookups = append(lookups, symbolLookup{"findTypeByName", pattern})
+		}
+
+		// Smart routing: detect function definition patterns
+		if m := funcDefRe.FindStringSubmatch(pattern); m != nil {
+			lookups = append(lookups, symbolLookup{"findMember", m[1]})
+		}
+
+		if result := smartRoute(hookCtx, symbolTargets, lookups, keep); result != "" {
+			deny(result)
+		}
+		// A miss falls through to the grep, already under way
 	}
 
-	var formatted string
-	switch mode {
-	case "files_with_matches":
-		seen := map[string]bool{}
-		var files []string
-		for _, r := range data.Results {
-			if !seen[r.File] {
-				seen[r.File] = true
-				files = append(files, r.File)
-			}
+	<-searched
+
+	// head_limit applies to files in the aggregated modes, as in native Grep
+	if outputMode != "content" {
+		files = dropVanishedFiles(files, offset+maxRes)
+		if !ok || len(files) == 0 {
+			allow()
 		}
-		formatted = strings.Join(files, "\n")
-	case "count":
-		counts := map[string]int{}
-		var order []string
-		for _, r := range data.Results {
-			if counts[r.File] == 0 {
-				order = append(order, r.File)
-			}
-			counts[r.File]++
+		total := 0
+		for _, f := range files {
+			total += f.Count
 		}
-		var lines []string
-		for _, f := range order {
-			lines = append(lines, fmt.Sprintf("%s: %d", f, counts[f]))
+		if offset >= len(files) {
+			deny(fmt.Sprintf(
+				"[unreal-index] Grep intercepted — no more results for \"%s\": "+
+					"%d files match, offset %d is past the end.", pattern, len(files), offset))
 		}
-		formatted = strings.Join(lines, "\n")
-	default:
+		shown := files[offset:]
+		if len(shown) > maxRes {
+			shown = shown[:maxRes]
+		}
+		labeled := len(targets) > 1
 		var lines []string
-		for _, r := range data.Results {
-			ln := fmt.Sprintf("%s:%d: %s", r.File, r.Line, r.Match)
-			for _, c := range r.Context {
-				ln += "\n  " + c
+		for _, f := range shown {
+			label := ""
+			if labeled {
+				label = "[" + f.Workspace + "] "
 			}
-			lines = append(lines, ln)
+			switch {
+			case ripgrepOutput() && outputMode == "count":
+				lines = append(lines, fmt.Sprintf("%s:%d", absResultPath(f.File), f.Count))
+			case ripgrepOutput():
+				lines = append(lines, absResultPath(f.File))
+			case outputMode == "count":
+				lines = append(lines, fmt.Sprintf("%s%s: %d", label, f.File, f.Count))
+			default:
+				lines = append(lines, label+f.File)
+			}
+		}
+		summary := fmt.Sprintf(" (%d files, %d matching lines)", len(files), total)
+		if len(shown) < len(files) {
+			summary = fmt.Sprintf(" (files %d-%d of %d, %d matching lines)", offset+1, offset+len(shown), len(files), total)
+		}
+		if partial {
+			summary += " (partial: the index stopped counting; totals are lower bounds)"
+		}
+		if note := unavailableNote(unavailable); note != "" {
+			summary += " (" + note + ")"
+		}
+		next := ""
+		if offset+len(shown) < len(files) {
+			next = "\n\n" + nextPageCall(ti, offset+len(shown))
+		}
+		if ripgrepOutput() {
+			var cut []string
+			if len(shown) < len(files) {
+				cut = append(cut, fmt.Sprintf("files %d-%d of %d", offset+1, offset+len(shown), len(files)))
+			}
+			if partial {
+				cut = append(cut, "the index stopped counting; totals are lower bounds")
+			}
+			if note := unavailableNote(unavailable); note != "" {
+				cut = append(cut, note)
+			}
+			deny(strings.Join(lines, "\n") + ripgrepTruncated(cut...) + next)
 		}
-		formatted = strings.Join(lines, "\n")
+		deny(fmt.Sprintf(
+			"[unreal-index] Grep intercepted — indexed results for \"%s\"%s:\n\n%s\n\n"+
+				"Results from pre-built index. To search a specific file use Read. "+
+				"To search outside the indexed project, ask the user to allow direct Grep.%s",
+			pattern, summary, strings.Join(lines, "\n"), next))
 	}
 
-	trunc := ""
-	if data.Truncated {
-		trunc = fmt.Sprintf(" (%d of %d)", len(data.Results), data.TotalMatches)
+	if !ok {
+		allow()
+	}
+
+	var results []GrepResult
+	for _, r := range data.Results {
+		if keep(r.File) {
+			results = append(results, r)
+		}
+	}
+	if local {
+		results = results[min(offset, len(results)):]
+	}
+	if len(results) == 0 {
+		// A later page that comes up empty is the end, not a miss
+		if offset > 0 && local && data.Truncated {
+			deny(fmt.Sprintf(
+				"[unreal-index] Grep intercepted — no more results for \"%s\": "+
+					"paging stops after the first %d index hits; narrow path or pattern for the rest.", pattern, fetch))
+		}
+		if offset > 0 {
+			deny(fmt.Sprintf(
+				"[unreal-index] Grep intercepted — no more results for \"%s\": "+
+					"offset %d is past the end.", pattern, offset))
+		}
+		allow()
+	}
+	// A page sliced here can only continue inside the fetched hits
+	truncated := len(results) > maxRes || (!local && data.Truncated)
+	exhausted := local && data.Truncated && !truncated
+	if len(results) > maxRes {
+		results = results[:maxRes]
+	}
+	// Paging counts index hits, including any verification drops below
+	consumed := len(results)
+	results, report := verifyGrepResults(results, before, after, ripgrepOutput())
+	if len(results) == 0 && !truncated {
+		allow()
+	}
+	stop := ""
+	if exhausted {
+		stop = fmt.Sprintf("paging stops after the first %d index hits; narrow path or pattern for the rest", fetch)
+	}
+
+	if ripgrepOutput() {
+		_, hasN := ti["-n"]
+		out := formatRipgrep(results, before, after, true, !hasN || flagVal(ti, "-n"))
+		var cut []string
+		if stop != "" {
+			cut = append(cut, stop)
+		}
+		if note := unavailableNote(data.Unavailable); note != "" {
+			cut = append(cut, note)
+		}
+		out += ripgrepTruncated(cut...)
+		if truncated {
+			out += "\n\n" + nextPageCall(ti, offset+consumed)
+		}
+		deny(out)
+	}
+
+	labeled := len(targets) > 1
+	var lines []string
+	for _, r := range results {
+		ln := fmt.Sprintf("%s:%d: %s", r.File, r.Line, r.Match)
+		if labeled {
+			ln = "[" + r.Workspace + "] " + ln
+		}
+		if r.Stale {
+			ln += "  [changed on disk]"
+		}
+		for _, c := range r.Context {
+			ln += "\n  " + c
+		}
+		lines = append(lines, ln)
 	}
 
+	trunc, next := "", ""
+	if truncated {
+		// The service total counts hits outside the requested scope
+		trunc = fmt.Sprintf(" (results %d-%d)", offset+1, offset+consumed)
+		if !filtered {
+			trunc = fmt.Sprintf(" (results %d-%d of %d)", offset+1, offset+consumed, data.TotalMatches)
+		}
+		next = "\n\n" + nextPageCall(ti, offset+consumed)
+	}
+	if stop != "" {
+		trunc += " (" + stop + ")"
+	}
+	if note := unavailableNote(data.Unavailable); note != "" {
+		trunc += " (" + note + ")"
+	}
+	trunc += report.String()
+
 	deny(fmt.Sprintf(
 		"[unreal-index] Grep intercepted — indexed results for \"%s\"%s:\n\n%s\n\n"+
 			"Results from pre-built index. To search a specific file use Read. "+
-			"To search outside the indexed project, ask the user to allow direct Grep.",
-		pattern, trunc, formatted))
+			"To search outside the indexed project, ask the user to allow direct Grep.%s",
+		pattern, trunc, strings.Join(lines, "\n"), next))
 }
 
-// ── Glob handler ─────────────────────────────────────────────
+// ripgrepTruncated is the line appended to byte-for-byte ripgrep output
+// that is incomplete, so the model can tell; empty when nothing was cut.
+func ripgrepTruncated(cut ...string) string {
+	if len(cut) == 0 {
+		return ""
+	}
+	return "\n[unreal-index] truncated: " + strings.Join(cut, "; ")
+}
 
-func handleGlob(ti map[string]interface{}) {
-	pattern := str(ti, "pattern")
-	path := str(ti, "path")
+// nextPageCall returns the Grep call that fetches the page after this one:
+// the same input with offset advanced.
+func nextPageCall(ti map[string]interface{}, offset int) string {
+	next := map[string]interface{}{}
+	for k, v := range ti {
+		next[k] = v
+	}
+	next["offset"] = offset
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(next)
+	return "Next page: Grep " + strings.TrimSpace(b.String())
+}
 
-	// Determine the effective search directory from path or glob pattern prefix
-	searchDir := path
-	if searchDir == "" {
-		if idx := strings.IndexAny(pattern, "*?"); idx > 0 {
-			prefix := pattern[:idx]
-			if lastSep := strings.LastIndexAny(prefix, "/\\"); lastSep >= 0 {
-				searchDir = prefix[:lastSep]
+// grepByFile runs a /grep query in aggregate mode against every target and
+// returns per-file matching-line counts for the whole corpus, newest files
+// first, keeping only files that pass keep. partial is set when a service
+// stopped early and unavailable names the workspaces that failed; ok is
+// false only when every workspace failed.
+func grepByFile(targets []workspaceRoute, query url.Values, keep func(string) bool) (files []GrepFileCount, partial bool, unavailable []string, ok bool) {
+	p := url.Values{}
+	for k, v := range query {
+		p[k] = v
+	}
+	p.Set("aggregate", "files")
+	p.Del("maxResults")
+	p.Del("contextLines")
+	p.Del("offset")
+
+	responses := fanOut(targets, func(ws workspaceRoute) *GrepFilesResponse {
+		var data GrepFilesResponse
+		if !fetchJSON(ws.URL+"/grep?"+p.Encode(), &data) || data.Error != "" {
+			direct, found := zoektGrepFiles(ws, p)
+			if !found {
+				return nil
 			}
+			data = direct
+		}
+		for i := range data.Files {
+			data.Files[i].Workspace = ws.label()
 		}
+		return &data
+	})
+	var lists [][]GrepFileCount
+	for i, data := range responses {
+		if data == nil {
+			unavailable = append(unavailable, targets[i].label())
+			continue
+		}
+		ok = true
+		partial = partial || data.Truncated
+		var kept []GrepFileCount
+		for _, f := range data.Files {
+			if keep(f.File) {
+				kept = append(kept, f)
+			}
+		}
+		lists = append(lists, kept)
+	}
+	return interleave(lists, func(f GrepFileCount) string { return f.File }), partial, unavailable, ok
+}
+
+// ── Glob handler ─────────────────────────────────────────────
+
+// joinGlobBase is the directory a glob's literal leading directories name
+// under root, or the literal directories themselves for an absolute glob
+// (root "").
+func joinGlobBase(root, litDir string) string {
+	switch {
+	case root == "":
+		return litDir
+	case litDir != "":
+		return root + "/" + litDir
 	}
-	if !isInsideIndex(searchDir) {
+	return root
+}
+
+func handleGlob(ti map[string]interface{}) {
+	pattern := strings.ReplaceAll(str(ti, "pattern"), "\\", "/")
+	searchPath := str(ti, "path")
+	if pattern == "" {
 		allow()
 	}
+	alts := expandBraces(pattern)
+
+	// Patterns naming file types the index does not hold must hit the disk.
+	// When every alternative names an indexed type, an empty answer is final.
+	complete := true
+	for _, alt := range alts {
+		ext := strings.ToLower(path.Ext(alt[strings.LastIndex(alt, "/")+1:]))
+		if ext == "" || strings.ContainsAny(ext, "*?[") {
+			complete = false
+		} else if extLanguages[ext] == "" {
+			allow()
+		}
+	}
 
-	basename := pattern
-	if idx := strings.LastIndexAny(basename, "/\\"); idx >= 0 {
-		basename = basename[idx+1:]
+	var litDirs []string
+	for _, alt := range alts {
+		dir, _ := globLiteralDir(alt)
+		litDirs = append(litDirs, dir)
+	}
+	litDir := commonDir(litDirs)
+	lits, byName := globNameLiterals(alts)
+	if !byName {
+		lits = nil
+	}
+
+	// Roots the pattern is relative to: none for absolute patterns, the path
+	// argument, the working directory as for native Glob, or every project
+	// root when the search directory is unknown or, without a path, the
+	// working directory lies outside the index
+	explicit := searchPath != ""
+	if !explicit {
+		searchPath = hookCwd
+	}
+	searchPath = resolveHookPath(searchPath)
+	var roots []string
+	fromProjects := false
+	switch {
+	case isAbsShellPath(alts[0]):
+		roots = []string{""}
+	case isAbsShellPath(searchPath) && (explicit || isInsideIndex(joinGlobBase(slashPath(searchPath), litDir))):
+		roots = []string{slashPath(searchPath)}
+	default:
+		fromProjects = true
+		for _, proj := range indexProjects {
+			for _, root := range proj.Paths {
+				roots = append(roots, slashPath(root))
+			}
+		}
+		if len(roots) == 0 {
+			allow()
+		}
 	}
-	cleaned := strings.NewReplacer("*", "", "?", "").Replace(basename)
-	if idx := strings.LastIndex(cleaned, "."); idx >= 0 {
-		cleaned = cleaned[:idx]
+
+	var matches []string
+	partial, offline := false, false
+	searched := false
+	seen := map[string]bool{}
+	for _, root := range roots {
+		base := joinGlobBase(root, litDir)
+		if !isInsideIndex(base) {
+			continue
+		}
+		if staleInScope([]string{base}) {
+			allow()
+		}
+		files, capped, fromDB, err := indexedFilesUnder(base, lits)
+		if err == errNotIndexed {
+			continue
+		}
+		if err != nil {
+			allow()
+		}
+		searched = true
+		partial = partial || capped
+		offline = offline || fromDB
+		for _, f := range files {
+			target := f
+			if root != "" {
+				target, _ = relUnder(root, f)
+			}
+			for _, alt := range alts {
+				if globMatch(alt, target) && !seen[f] {
+					seen[f] = true
+					matches = append(matches, f)
+				}
+			}
+		}
 	}
-	if len(cleaned) < 3 {
+	// The index may still list files deleted since it was built
+	var onDisk []string
+	for _, f := range matches {
+		if !vanished(f) {
+			onDisk = append(onDisk, f)
+		}
+	}
+	matches = onDisk
+	// A stale database can't rule a file out
+	if !searched || len(matches) == 0 && (!complete || partial || offline) {
 		allow()
 	}
+	if len(matches) == 0 {
+		deny(globListing(pattern, nil, fromProjects, partial, false))
+	}
 
-	svcURL := resolveServiceURL(searchDir)
+	// Like Glob, most recently modified first
+	mtimes := map[string]int64{}
+	for _, f := range matches {
+		if st, err := os.Stat(f); err == nil {
+			mtimes[f] = st.ModTime().UnixNano()
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if mtimes[matches[i]] != mtimes[matches[j]] {
+			return mtimes[matches[i]] > mtimes[matches[j]]
+		}
+		return matches[i] < matches[j]
+	})
 
-	p := url.Values{}
-	p.Set("filename", cleaned)
-	p.Set("maxResults", "30")
+	deny(globListing(pattern, matches, fromProjects, partial, offline))
+}
 
-	var data FindFileResponse
-	if !fetchJSON(svcURL+"/find-file?"+p.Encode(), &data) || data.Error != "" || len(data.Results) == 0 {
-		allow()
+// globListing prints Glob matches, sorted as Glob sorts them, with the
+// intercept banner or, for ripgrep output, as the bare path list the native
+// tool prints.
+func globListing(pattern string, matches []string, fromProjects, partial, offline bool) string {
+	if len(matches) == 0 {
+		if ripgrepOutput() {
+			return "No files found"
+		}
+		return fmt.Sprintf(
+			"[unreal-index] Glob intercepted — no indexed files match \"%s\".\n\n"+
+				"No files found.", pattern)
 	}
 
-	var files []string
-	for _, r := range data.Results {
-		files = append(files, r.File)
+	const limit = 100
+	note := ""
+	var cut []string
+	// Absolute paths show which project root matched, so ripgrep output
+	// needs no note for that
+	if fromProjects {
+		note = " (relative to each indexed project root)"
 	}
-
-	deny(fmt.Sprintf(
-		"[unreal-index] Glob intercepted — indexed results for \"%s\":\n\n%s\n\n"+
+	if len(matches) > limit {
+		note += fmt.Sprintf(" (first %d of %d)", limit, len(matches))
+		cut = append(cut, fmt.Sprintf("first %d of %d files", limit, len(matches)))
+		matches = matches[:limit]
+	} else if partial {
+		note += " (partial: index listing truncated)"
+		cut = append(cut, "the index listing was cut short")
+	}
+	if offline {
+		note += " (" + offlineListing + ")"
+		cut = append(cut, offlineListing)
+	}
+	if ripgrepOutput() {
+		return strings.Join(matches, "\n") + ripgrepTruncated(cut...)
+	}
+	return fmt.Sprintf(
+		"[unreal-index] Glob intercepted — indexed results for \"%s\"%s:\n\n%s\n\n"+
 			"Results from pre-built index. "+
 			"To search outside the indexed project, ask the user to allow direct Glob.",
-		pattern, strings.Join(files, "\n")))
+		pattern, note, strings.Join(matches, "\n"))
 }
 
 // ── Bash handler ─────────────────────────────────────────────
 
 func handleBash(ti map[string]interface{}) {
 	cmd := str(ti, "command")
-	if cmd == "" {
+	if strings.TrimSpace(cmd) == "" {
 		allow()
 	}
 
-	// Trim leading whitespace for matching
-	trimmed := strings.TrimSpace(cmd)
-
-	// Bypass: git commands are always allowed through
-	if strings.HasPrefix(trimmed, "git ") || trimmed == "git" {
+	// Unparseable commands (heredocs, unbalanced quotes) are left alone
+	list, err := parseShell(cmd)
+	if err != nil {
 		allow()
 	}
 
-	// Bypass: if the command targets a path outside the indexed projects, allow through
-	shellPath := extractShellTargetPath(trimmed)
-	if shellPath != "" && !isInsideIndex(shellPath) {
-		allow()
+	// Each simple command is judged on its own: the first one that should be
+	// rerouted decides the response, everything else passes through.
+	for _, sc := range flattenShell(list, hookCwd) {
+		handleShellCommand(sc)
 	}
+	allow()
+}
 
-	svcURL := resolveServiceURL(shellPath)
+// handleShellCommand denies if the simple command should be rerouted to the
+// index and returns otherwise.
+func handleShellCommand(sc shellCommand) {
+	args := sc.Args
+	switch sc.Name() {
+	case "git":
+		// Bypass: git commands are always allowed through
+		return
 
 	// A. Directory listing: ls, dir, tree → block, redirect to Glob
-	if lsRe.MatchString(trimmed) {
+	case "ls", "dir", "tree":
+		if !shellTargetsInsideIndex(sc, extractShellTargetPath(sc.Name(), args)) {
+			return
+		}
 		deny(
 			"[unreal-index] Directory listing commands (ls, dir, tree) are blocked.\n\n" +
 				"Use Glob to find files by pattern (e.g., Glob with pattern \"**/*.as\") " +
 				"or Read to view a specific file. " +
 				"Glob is intercepted by unreal-index for fast indexed results.")
-	}
 
-	// B. Find commands → extract -name and proxy to /find-file, or block
-	if findRe.MatchString(trimmed) {
-		if m := findNameRe.FindStringSubmatch(trimmed); m != nil {
-			// Extract filename, strip glob chars
-			name := strings.NewReplacer("*", "", "?", "").Replace(m[1])
-			if idx := strings.LastIndex(name, "."); idx >= 0 {
-				name = name[:idx]
+	// B. Find commands → evaluate the expression against indexed files, or block
+	case "find":
+		q := parseFindArgs(args)
+		inside := false
+		for _, start := range q.Starts {
+			if shellTargetsInsideIndex(sc, start) {
+				inside = true
 			}
-			if len(name) >= 3 {
-				p := url.Values{}
-				p.Set("filename", name)
-				p.Set("maxResults", "30")
-
-				var data FindFileResponse
-				if fetchJSON(svcURL+"/find-file?"+p.Encode(), &data) && data.Error == "" && len(data.Results) > 0 {
-					var files []string
-					for _, r := range data.Results {
-						files = append(files, r.File)
-					}
-					deny(fmt.Sprintf(
-						"[unreal-index] find command intercepted — indexed results for \"%s\":\n\n%s\n\n"+
-							"Results from pre-built index. Use Glob for file searches.",
-						name, strings.Join(files, "\n")))
-				}
+		}
+		if !inside {
+			return
+		}
+		// Name patterns for file types the index does not hold must hit the disk
+		for _, ext := range q.Expr.NameExts() {
+			if extLanguages[ext] == "" {
+				return
+			}
+		}
+		var dirs []string
+		for _, start := range q.Starts {
+			dirs = append(dirs, canonicalPath(joinShellPath(sc.Dir, start)))
+		}
+		if staleInScope(dirs) {
+			return
+		}
+		if q.Unsupported == "" {
+			if reason := shellFind(sc, q); reason != "" {
+				deny(reason)
 			}
 		}
-		// No -name or no results — still block the find command
 		deny(
 			"[unreal-index] find commands are blocked.\n\n" +
 				"Use Glob to find files by pattern (intercepted by unreal-index for fast results) " +
 				"or Read to view specific files.")
-	}
 
 	// C. Shell grep/rg → extract pattern and proxy to /grep
-	if grepRe.MatchString(trimmed) {
-		if m := shellGrepPatternRe.FindStringSubmatch(trimmed); m != nil {
-			// Pick the matched group: m[1]=double-quoted, m[2]=single-quoted, m[3]=unquoted
-			pattern := m[1]
-			if pattern == "" {
-				pattern = m[2]
-			}
-			if pattern == "" {
-				pattern = m[3]
-			}
-			// Convert basic grep alternation \| to regex |
-			pattern = strings.ReplaceAll(pattern, `\|`, "|")
-			// Strip other basic grep escapes: \( \) \+ \?
-			for _, esc := range []string{`\(`, `\)`, `\+`, `\?`} {
-				pattern = strings.ReplaceAll(pattern, esc, esc[1:])
-			}
-
-			if len(pattern) >= 2 {
-				p := url.Values{}
-				p.Set("pattern", pattern)
-				p.Set("maxResults", "30")
-				p.Set("grouped", "false")
-				p.Set("symbols", "false")
+	case "grep", "egrep", "fgrep", "rg":
+		g := parseGrepInvocation(sc.Name(), args)
+		// A grep fed by a pipe with no file operands is filtering output, not searching files
+		if sc.Piped && len(g.Operands) == 0 {
+			return
+		}
+		// Targets are specific files — the index greps whole projects
+		if len(g.Operands) > 0 && allShellFiles(g.Operands) {
+			return
+		}
+		// Without -r grep reads a directory operand as a file and finds nothing
+		if !g.Recursive {
+			return
+		}
+		// The index counts matching lines; only rg can count the matches in them
+		if g.CountMatches {
+			return
+		}
+		target := extractShellTargetPath(sc.Name(), args)
+		if !shellTargetsInsideIndex(sc, target) {
+			return
+		}
+		dir := sc.Dir
+		if target != "" {
+			dir = canonicalPath(joinShellPath(sc.Dir, target))
+		}
+		if staleInScope([]string{dir}) {
+			return
+		}
+		targets := searchTargets(dir)
 
-				var data GrepResponse
-				if fetchJSON(svcURL+"/grep?"+p.Encode(), &data) && data.Error == "" && len(data.Results) > 0 {
-					var lines []string
-					for _, r := range data.Results {
-						lines = append(lines, fmt.Sprintf("%s:%d: %s", r.File, r.Line, r.Match))
-					}
-					trunc := ""
-					if data.Truncated {
-						trunc = fmt.Sprintf(" (%d of %d)", len(data.Results), data.TotalMatches)
-					}
-					deny(fmt.Sprintf(
-						"[unreal-index] grep/rg intercepted — indexed results for \"%s\"%s:\n\n%s\n\n"+
-							"Results from pre-built index. Use the Grep tool instead of shell grep.",
-						pattern, trunc, strings.Join(lines, "\n")))
-				}
+		// Flags we cannot reproduce (-v, -L, -f, ...) get the generic block below
+		if g.Unsupported == "" {
+			if result := shellGrep(targets, g); result != "" {
+				deny(result)
 			}
 		}
 		// No extractable pattern or no results — still block
 		deny(
 			"[unreal-index] Shell grep/rg commands are blocked.\n\n" +
 				"Use the Grep tool instead (intercepted by unreal-index for fast indexed results).")
-	}
 
 	// D. File read commands: cat, head, tail → block, redirect to Read tool
-	if catRe.MatchString(trimmed) {
+	case "cat", "head", "tail":
+		target := extractShellTargetPath(sc.Name(), args)
+		if sc.Piped && target == "" || !shellTargetsInsideIndex(sc, target) {
+			return
+		}
 		deny(
 			"[unreal-index] File read commands (cat, head, tail) are blocked.\n\n" +
 				"Use the Read tool instead for better performance and proper file access. " +
 				"Example: Read tool with file_path parameter.")
-	}
 
 	// E. Word count: wc → block, redirect to Read tool
-	if wcRe.MatchString(trimmed) {
+	case "wc":
+		target := extractShellTargetPath(sc.Name(), args)
+		if sc.Piped && target == "" || !shellTargetsInsideIndex(sc, target) {
+			return
+		}
 		deny(
 			"[unreal-index] wc is blocked.\n\n" +
 				"Use the Read tool instead — it displays line numbers (cat -n format), " +
 				"so the last line number gives you the total line count.")
-	}
 
 	// F. PowerShell commands: Get-ChildItem, Select-String, Get-Content
-	if powershellRe.MatchString(trimmed) {
-		// Get-ChildItem / gci → file search, proxy to /find-file
-		if getChildItemRe.MatchString(trimmed) {
-			if m := psFilterRe.FindStringSubmatch(trimmed); m != nil {
-				name := strings.NewReplacer("*", "", "?", "").Replace(m[1])
-				if idx := strings.LastIndex(name, "."); idx >= 0 {
-					name = name[:idx]
+	case "powershell", "pwsh":
+		handlePowerShell(strings.Join(args[1:], " "))
+	}
+}
+
+// shellTargetsInsideIndex resolves a command's target path against the cd
+// directory and reports whether it may be inside the index. Relative targets
+// that cannot be resolved are assumed to be inside.
+func shellTargetsInsideIndex(sc shellCommand, target string) bool {
+	if target == "" {
+		return isInsideIndex(sc.Dir)
+	}
+	return isInsideIndex(canonicalPath(joinShellPath(sc.Dir, target)))
+}
+
+// allShellFiles reports whether every operand names a specific source file.
+func allShellFiles(operands []string) bool {
+	for _, op := range operands {
+		if !fileExtRe.MatchString(op) {
+			return false
+		}
+	}
+	return true
+}
+
+// extractShellTargetPath returns the directory or file a parsed command
+// operates on, or "" if it has none.
+func extractShellTargetPath(name string, args []string) string {
+	switch name {
+	case "grep", "egrep", "fgrep", "rg":
+		// Last operand after the pattern
+		if operands := parseGrepInvocation(name, args).Operands; len(operands) > 0 {
+			return operands[len(operands)-1]
+		}
+	default:
+		// ls, cat, head, tail, wc: first non-flag operand
+		for i := 1; i < len(args); i++ {
+			if strings.HasPrefix(args[i], "-") && args[i] != "-" {
+				if (name == "head" || name == "tail") && (args[i] == "-n" || args[i] == "-c") {
+					i++
 				}
-				if len(name) >= 3 {
-					p := url.Values{}
-					p.Set("filename", name)
-					p.Set("maxResults", "30")
-
-					var data FindFileResponse
-					if fetchJSON(svcURL+"/find-file?"+p.Encode(), &data) && data.Error == "" && len(data.Results) > 0 {
-						var files []string
-						for _, r := range data.Results {
-							files = append(files, r.File)
-						}
-						deny(fmt.Sprintf(
-							"[unreal-index] PowerShell Get-ChildItem intercepted — indexed results for \"%s\":\n\n%s\n\n"+
-								"Results from pre-built index. Use the Glob tool or unreal_find_file MCP tool instead of PowerShell.",
-							name, strings.Join(files, "\n")))
-					}
+				continue
+			}
+			return args[i]
+		}
+	}
+	return ""
+}
+
+// shellGrep runs a translated grep/rg command against /grep and formats the
+// hits the way the command prints them. Returns "" if there is nothing to show.
+func shellGrep(targets []workspaceRoute, g grepInvocation) string {
+	pattern := g.Regex()
+	if len(pattern) < 2 {
+		return ""
+	}
+
+	const limit = 30
+	fetch := limit
+	// Over-fetch when client-side filters will discard hits
+	if g.HasFilters() || g.MaxCount > 0 {
+		fetch = limit * 5
+	}
+
+	p := url.Values{}
+	p.Set("pattern", pattern)
+	p.Set("maxResults", fmt.Sprintf("%d", fetch))
+	p.Set("grouped", "false")
+	p.Set("symbols", "false")
+	if !g.CaseSensitive {
+		p.Set("caseSensitive", "false")
+	}
+	if ctx := max(g.Before, g.After); ctx > 0 && !g.FilesOnly && !g.Count {
+		p.Set("contextLines", fmt.Sprintf("%d", ctx))
+	}
+	if lang := g.Language(); lang != "" {
+		p.Set("language", lang)
+	}
+	if ripgrepOutput() {
+		p.Set("rawLines", "true")
+	}
+
+	// -l and -c need every matching file, not the first page of line hits
+	if g.FilesOnly || g.Count {
+		files, partial, unavailable, ok := grepByFile(targets, p, g.Keep)
+		files = dropVanishedFiles(files, limit)
+		if !ok || len(files) == 0 {
+			return ""
+		}
+		var lines []string
+		for _, f := range files {
+			if ripgrepOutput() {
+				f.File = absResultPath(f.File)
+			}
+			count := f.Count
+			if g.MaxCount > 0 {
+				count = min(count, g.MaxCount)
+			}
+			switch {
+			case g.FilesOnly:
+				lines = append(lines, f.File)
+			case g.NoFilename:
+				lines = append(lines, fmt.Sprintf("%d", count))
+			default:
+				lines = append(lines, fmt.Sprintf("%s:%d", f.File, count))
+			}
+		}
+		trunc := ""
+		var cut []string
+		if len(lines) > limit {
+			trunc = fmt.Sprintf(" (first %d of %d files)", limit, len(lines))
+			cut = append(cut, fmt.Sprintf("first %d of %d files", limit, len(lines)))
+			lines = lines[:limit]
+		}
+		if partial {
+			trunc += " (partial: the index stopped counting)"
+			cut = append(cut, "the index stopped counting; totals are lower bounds")
+		}
+		if note := unavailableNote(unavailable); note != "" {
+			trunc += " (" + note + ")"
+			cut = append(cut, note)
+		}
+		if ripgrepOutput() {
+			return strings.Join(lines, "\n") + ripgrepTruncated(cut...)
+		}
+		return fmt.Sprintf(
+			"[unreal-index] grep/rg intercepted — indexed results for \"%s\"%s:\n\n%s\n\n"+
+				"Results from pre-built index. Use the Grep tool instead of shell grep.",
+			pattern, trunc, strings.Join(lines, "\n"))
+	}
+
+	data, ok := grepAll(targets, p)
+	if !ok || len(data.Results) == 0 {
+		return ""
+	}
+
+	// Apply --include/-g/-t filters and -m per-file limits
+	var results []GrepResult
+	perFile := map[string]int{}
+	for _, r := range data.Results {
+		if !g.Keep(r.File) {
+			continue
+		}
+		if g.MaxCount > 0 && perFile[r.File] >= g.MaxCount {
+			continue
+		}
+		perFile[r.File]++
+		results = append(results, r)
+	}
+	results, report := verifyGrepResults(results, g.Before, g.After, ripgrepOutput())
+	if len(results) == 0 {
+		return ""
+	}
+
+	if ripgrepOutput() && !g.OnlyMatching {
+		var cut []string
+		if data.Truncated || len(results) > limit {
+			results = results[:min(limit, len(results))]
+			cut = append(cut, fmt.Sprintf("first %d matching lines", len(results)))
+		}
+		if note := unavailableNote(data.Unavailable); note != "" {
+			cut = append(cut, note)
+		}
+		return formatRipgrep(results, g.Before, g.After, !g.NoFilename, g.LineNumbers) + ripgrepTruncated(cut...)
+	}
+
+	var onlyRe *regexp.Regexp
+	var lines []string
+	if g.OnlyMatching {
+		expr := pattern
+		if !g.CaseSensitive {
+			expr = "(?i)" + expr
+		}
+		onlyRe, _ = regexp.Compile(expr)
+	}
+	for _, r := range results {
+		prefix := ""
+		if !g.NoFilename {
+			prefix = r.File + ":"
+		}
+		if g.LineNumbers {
+			prefix += fmt.Sprintf("%d:", r.Line)
+		}
+		if onlyRe != nil {
+			for _, m := range onlyRe.FindAllString(r.Match, -1) {
+				lines = append(lines, prefix+m)
+			}
+			continue
+		}
+		ln := prefix + r.Match
+		if r.Stale {
+			ln += "  [changed on disk]"
+		}
+		for _, c := range r.Context {
+			ln += "\n  " + c
+		}
+		lines = append(lines, ln)
+	}
+	trunc := ""
+	if data.Truncated || len(lines) > limit {
+		if len(lines) > limit {
+			lines = lines[:limit]
+		}
+		// Totals are only meaningful when every hit is printed as one line
+		trunc = fmt.Sprintf(" (first %d)", len(lines))
+		if !g.HasFilters() && g.MaxCount == 0 && !g.OnlyMatching {
+			trunc = fmt.Sprintf(" (%d of %d)", len(lines), data.TotalMatches)
+		}
+	}
+	if note := unavailableNote(data.Unavailable); note != "" {
+		trunc += " (" + note + ")"
+	}
+	trunc += report.String()
+
+	return fmt.Sprintf(
+		"[unreal-index] grep/rg intercepted — indexed results for \"%s\"%s:\n\n%s\n\n"+
+			"Results from pre-built index. Use the Grep tool instead of shell grep.",
+		pattern, trunc, strings.Join(lines, "\n"))
+}
+
+// errNotIndexed reports a directory that no indexed project overlaps.
+var errNotIndexed = errors.New("not inside an indexed project")
+
+// indexedFilesUnder lists the host paths of indexed files at or below dir.
+// With name literals, only files whose basename contains one are fetched from
+// /find-file; otherwise every file of the modules under dir comes from
+// /browse-module. partial is set when the service capped a listing, and
+// offline when the names came from the workspace database (see offline.go).
+func indexedFilesUnder(dir string, lits []string) (files []string, partial, offline bool, err error) {
+	locs := projectRootsUnder(dir)
+	if loc, ok := locateHostPath(dir); ok {
+		locs = []indexLocation{loc}
+	}
+	if len(locs) == 0 {
+		return nil, false, false, errNotIndexed
+	}
+	svcURL := resolveServiceURL(dir)
+
+	var indexPaths []string
+	if len(lits) > 0 {
+		const fetch = 500
+		queries := make([]batchQuery, len(lits))
+		for i, lit := range lits {
+			queries[i] = batchQuery{Method: "findFileByName", Args: []interface{}{lit, map[string]interface{}{"maxResults": fetch}}}
+		}
+		results, fromDB, ok := indexLookup(hookCtx, svcURL, queries)
+		if !ok {
+			return nil, false, false, errors.New("find-file failed")
+		}
+		offline = fromDB
+		for _, raw := range results {
+			if raw == nil {
+				return nil, false, false, errors.New("find-file failed")
+			}
+			hits := batchResult[[]FindFileResult](raw)
+			for _, r := range hits {
+				indexPaths = append(indexPaths, r.File)
+			}
+			partial = partial || len(hits) >= fetch
+		}
+	} else {
+		for _, loc := range locs {
+			p := url.Values{}
+			p.Set("module", loc.Module())
+			p.Set("project", loc.Project.Name)
+			p.Set("filesOnly", "true")
+			p.Set("maxResults", "20000")
+			var data BrowseModuleResponse
+			if !fetchJSON(svcURL+"/browse-module?"+p.Encode(), &data) || data.Error != "" {
+				return nil, false, false, errors.New("browse-module failed")
+			}
+			indexPaths = append(indexPaths, data.Files...)
+			partial = partial || data.Truncated
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, ip := range indexPaths {
+		hp := hostPath(ip)
+		if _, ok := relUnder(dir, hp); ok && !seen[hp] {
+			seen[hp] = true
+			files = append(files, hp)
+		}
+	}
+	return files, partial, offline, nil
+}
+
+// shellFind evaluates a parsed find command against the indexed files under
+// each start path and prints what find would. Returns "" if the start paths
+// cannot be resolved onto indexed projects.
+func shellFind(sc shellCommand, q findQuery) string {
+	const limit = 200
+	// Fetch by name when every match needs one, otherwise list whole modules
+	var lits []string
+	if l, ok := q.Expr.NameLiterals(); ok && q.Expr.FilesOnly() {
+		lits = l
+	}
+
+	var lines []string
+	hostOf := map[string]string{}
+	partial, offline := false, false
+	resolved := false
+	for _, start := range q.Starts {
+		dir := canonicalPath(joinShellPath(sc.Dir, start))
+		if dir == "" {
+			return ""
+		}
+		files, capped, fromDB, err := indexedFilesUnder(dir, lits)
+		if err == errNotIndexed {
+			continue
+		}
+		if err != nil {
+			return ""
+		}
+		resolved = true
+		partial = partial || capped
+		offline = offline || fromDB
+
+		printed := strings.TrimRight(start, "/")
+		if printed == "" {
+			printed = "/"
+		}
+		cands := []findCandidate{{Path: printed, Dir: true}}
+		seen := map[string]bool{printed: true}
+		for _, f := range files {
+			rel, _ := relUnder(dir, f)
+			if rel == "" {
+				continue
+			}
+			segs := strings.Split(rel, "/")
+			// The file itself, then each directory between it and the start
+			for depth := len(segs); depth > 0; depth-- {
+				p := strings.TrimSuffix(printed, "/") + "/" + strings.Join(segs[:depth], "/")
+				if seen[p] {
+					break
 				}
+				seen[p] = true
+				cands = append(cands, findCandidate{Path: p, Dir: depth < len(segs), Depth: depth})
 			}
-			deny(
-				"[unreal-index] PowerShell Get-ChildItem/gci is blocked.\n\n" +
-					"Use the Glob tool to find files by pattern (intercepted by unreal-index for fast results) " +
-					"or the unreal_find_file MCP tool for direct indexed search.")
+			hostOf[strings.TrimSuffix(printed, "/")+"/"+rel] = f
+		}
+		lines = append(lines, q.Run(cands)...)
+	}
+	if !resolved {
+		return ""
+	}
+
+	// Drop files deleted since indexing, checking only as many as are shown
+	var onDisk []string
+	for i, ln := range lines {
+		if len(onDisk) > limit {
+			onDisk = append(onDisk, lines[i:]...)
+			break
 		}
+		if !vanished(hostOf[ln]) {
+			onDisk = append(onDisk, ln)
+		}
+	}
+	lines = onDisk
+	// A stale database can't rule a file out
+	if len(lines) == 0 && offline {
+		return ""
+	}
 
-		// Select-String / sls → grep equivalent, proxy to /grep
-		if selectStringRe.MatchString(trimmed) {
-			if m := psPatternRe.FindStringSubmatch(trimmed); m != nil && len(m[1]) >= 2 {
+	return findListing(strings.Join(sc.Args, " "), lines, partial, offline)
+}
+
+// findListing prints the lines a find command matched, with the intercept
+// banner or, for ripgrep output, as the bare lines find prints.
+func findListing(cmd string, lines []string, partial, offline bool) string {
+	const limit = 200
+	if len(lines) == 0 {
+		// find prints nothing; say why so the reply isn't blank
+		if ripgrepOutput() {
+			return "[unreal-index] no indexed files match; the index holds source and config files only"
+		}
+		return fmt.Sprintf(
+			"[unreal-index] find intercepted — no indexed files match \"%s\".\n\n"+
+				"The index holds source and config files only. Use Glob for file searches.", cmd)
+	}
+	trunc := ""
+	var cut []string
+	if len(lines) > limit {
+		trunc = fmt.Sprintf(" (first %d of %d)", limit, len(lines))
+		cut = append(cut, fmt.Sprintf("first %d of %d lines", limit, len(lines)))
+		lines = lines[:limit]
+	} else if partial {
+		trunc = " (partial: module listing truncated)"
+		cut = append(cut, "the module listing was cut short")
+	}
+	if offline {
+		trunc += " (" + offlineListing + ")"
+		cut = append(cut, offlineListing)
+	}
+	if ripgrepOutput() {
+		return strings.Join(lines, "\n") + ripgrepTruncated(cut...)
+	}
+	return fmt.Sprintf(
+		"[unreal-index] find intercepted — indexed results for \"%s\"%s:\n\n%s\n\n"+
+			"Results from pre-built index (source and config files only). Use Glob for file searches.",
+		cmd, trunc, strings.Join(lines, "\n"))
+}
+
+// handlePowerShell proxies the PowerShell script passed to powershell/pwsh.
+func handlePowerShell(script string) {
+	svcURL := resolveServiceURL(hookCwd)
+
+	// Get-ChildItem / gci → file search, proxy to /find-file
+	if getChildItemRe.MatchString(script) {
+		if m := psFilterRe.FindStringSubmatch(script); m != nil {
+			name := strings.NewReplacer("*", "", "?", "").Replace(m[1])
+			if idx := strings.LastIndex(name, "."); idx >= 0 {
+				name = name[:idx]
+			}
+			if len(name) >= 3 {
 				p := url.Values{}
-				p.Set("pattern", m[1])
+				p.Set("filename", name)
 				p.Set("maxResults", "30")
-				p.Set("grouped", "false")
-				p.Set("symbols", "false")
 
-				var data GrepResponse
-				if fetchJSON(svcURL+"/grep?"+p.Encode(), &data) && data.Error == "" && len(data.Results) > 0 {
-					var lines []string
+				var data FindFileResponse
+				if fetchJSON(svcURL+"/find-file?"+p.Encode(), &data) && data.Error == "" && len(data.Results) > 0 {
+					var files []string
 					for _, r := range data.Results {
-						lines = append(lines, fmt.Sprintf("%s:%d: %s", r.File, r.Line, r.Match))
+						files = append(files, r.File)
 					}
 					deny(fmt.Sprintf(
-						"[unreal-index] PowerShell Select-String intercepted — indexed results for \"%s\":\n\n%s\n\n"+
-							"Results from pre-built index. Use the Grep tool or unreal_grep MCP tool instead of PowerShell.",
-						m[1], strings.Join(lines, "\n")))
+						"[unreal-index] PowerShell Get-ChildItem intercepted — indexed results for \"%s\":\n\n%s\n\n"+
+							"Results from pre-built index. Use the Glob tool or unreal_find_file MCP tool instead of PowerShell.",
+						name, strings.Join(files, "\n")))
 				}
 			}
-			deny(
-				"[unreal-index] PowerShell Select-String/sls is blocked.\n\n" +
-					"Use the Grep tool instead (intercepted by unreal-index for fast indexed results) " +
-					"or the unreal_grep MCP tool for direct indexed search.")
 		}
+		deny(
+			"[unreal-index] PowerShell Get-ChildItem/gci is blocked.\n\n" +
+				"Use the Glob tool to find files by pattern (intercepted by unreal-index for fast results) " +
+				"or the unreal_find_file MCP tool for direct indexed search.")
+	}
 
-		// Get-Content / gc / type → block, redirect to Read
-		if getContentRe.MatchString(trimmed) {
-			deny(
-				"[unreal-index] PowerShell Get-Content/gc is blocked.\n\n" +
-					"Use the Read tool instead for better performance and proper file access.")
+	// Select-String / sls → grep equivalent, proxy to /grep
+	if selectStringRe.MatchString(script) {
+		if m := psPatternRe.FindStringSubmatch(script); m != nil && len(m[1]) >= 2 {
+			p := url.Values{}
+			p.Set("pattern", m[1])
+			p.Set("maxResults", "30")
+			p.Set("grouped", "false")
+			p.Set("symbols", "false")
+
+			var data GrepResponse
+			if fetchJSON(svcURL+"/grep?"+p.Encode(), &data) && data.Error == "" && len(data.Results) > 0 {
+				var lines []string
+				for _, r := range data.Results {
+					lines = append(lines, fmt.Sprintf("%s:%d: %s", r.File, r.Line, r.Match))
+				}
+				deny(fmt.Sprintf(
+					"[unreal-index] PowerShell Select-String intercepted — indexed results for \"%s\":\n\n%s\n\n"+
+						"Results from pre-built index. Use the Grep tool or unreal_grep MCP tool instead of PowerShell.",
+					m[1], strings.Join(lines, "\n")))
+			}
 		}
+		deny(
+			"[unreal-index] PowerShell Select-String/sls is blocked.\n\n" +
+				"Use the Grep tool instead (intercepted by unreal-index for fast indexed results) " +
+				"or the unreal_grep MCP tool for direct indexed search.")
 	}
 
-	// G. Everything else → allow
-	allow()
+	// Get-Content / gc / type → block, redirect to Read
+	if getContentRe.MatchString(script) {
+		deny(
+			"[unreal-index] PowerShell Get-Content/gc is blocked.\n\n" +
+				"Use the Read tool instead for better performance and proper file access.")
+	}
 }
 
 // ── Main dispatch ────────────────────────────────────────────
@@ -772,6 +1775,22 @@ func main() {
 		allow()
 	}
 
+	hookCwd = canonicalPath(input.Cwd)
+	hookSession = input.SessionID
+	startBudget()
+
+	switch input.HookEventName {
+	case "PostToolUse":
+		handlePostToolUse(input.ToolName, input.ToolInput)
+		allow()
+	case "SessionStart":
+		handleSessionStart()
+		allow()
+	case "UserPromptSubmit":
+		handleUserPromptSubmit(input.Prompt)
+		allow()
+	}
+
 	switch input.ToolName {
 	case "Grep":
 		handleGrep(input.ToolInput)
@@ -779,6 +1798,10 @@ func main() {
 		handleGlob(input.ToolInput)
 	case "Bash":
 		handleBash(input.ToolInput)
+	case "Task":
+		handleTask(input.ToolInput)
+	case "Read":
+		handleRead(input.ToolInput)
 	default:
 		allow()
 	}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// ── On-disk verification ─────────────────────────────────────
+//
+// Index results can name files deleted since they were ingested, or quote
+// lines that have since moved or changed. Before a result is shown, the file
+// is checked on disk: vanished files are dropped, hits whose text moved are
+// relocated to the nearest line with the same text, and hits whose text is
+// gone are flagged (or dropped where the output must look like ripgrep's).
+
+// maxVerifyFileSize skips re-reading files too large to scan per call.
+const maxVerifyFileSize = 8 << 20
+
+// vanished reports whether a host file is gone from disk. Files whose project
+// root is itself missing (an index built on another machine) are not judged.
+func vanished(hp string) bool {
+	if hp == "" {
+		return false
+	}
+	if _, err := os.Stat(hp); !errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	loc, ok := locateHostPath(hp)
+	if !ok {
+		return false
+	}
+	_, err := os.Stat(loc.Base)
+	return err == nil
+}
+
+// dropVanishedFiles removes aggregated files that no longer exist, checking
+// files in order until need survivors are found; the rest are kept unchecked.
+func dropVanishedFiles(files []GrepFileCount, need int) []GrepFileCount {
+	var out []GrepFileCount
+	for i, f := range files {
+		if len(out) >= need {
+			return append(out, files[i:]...)
+		}
+		if !vanished(hostPath(f.File)) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// verifyReport counts what verification changed, for the result header.
+type verifyReport struct {
+	Vanished, Moved, Changed int
+}
+
+func (v verifyReport) String() string {
+	var parts []string
+	if v.Vanished > 0 {
+		parts = append(parts, fmt.Sprintf("%d dropped (file deleted)", v.Vanished))
+	}
+	if v.Moved > 0 {
+		parts = append(parts, fmt.Sprintf("%d relocated", v.Moved))
+	}
+	if v.Changed > 0 {
+		parts = append(parts, fmt.Sprintf("%d changed since indexing", v.Changed))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (verified on disk: " + strings.Join(parts, ", ") + ")"
+}
+
+// verifyGrepResults checks hits against the files on disk. Moved hits get
+// their line and context re-read with before/after lines of context; hits
+// whose text is gone are dropped when dropChanged is set and flagged
+// otherwise.
+func verifyGrepResults(results []GrepResult, before, after int, dropChanged bool) ([]GrepResult, verifyReport) {
+	var report verifyReport
+	files := map[string][]string{}
+	gone := map[string]bool{}
+	seen := map[string]bool{}
+	var out []GrepResult
+	for _, r := range results {
+		hp := hostPath(r.File)
+		lines, cached := files[hp]
+		if !cached && hp != "" {
+			gone[hp] = vanished(hp)
+			if !gone[hp] {
+				lines = readVerifyLines(hp)
+			}
+			files[hp] = lines
+		}
+		if gone[hp] {
+			report.Vanished++
+			continue
+		}
+		if lines != nil && !sameLine(lineAt(lines, r.Line), r.Match) {
+			if n := nearestLine(lines, r.Line, r.Match); n > 0 {
+				report.Moved++
+				r = relocate(r, lines, n, before, after)
+			} else {
+				report.Changed++
+				if dropChanged {
+					continue
+				}
+				r.Stale = true
+			}
+		}
+		key := fmt.Sprintf("%s:%d", r.File, r.Line)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, r)
+	}
+	return out, report
+}
+
+// readVerifyLines returns the file's lines, or nil if it cannot be checked.
+func readVerifyLines(hp string) []string {
+	st, err := os.Stat(hp)
+	if err != nil || st.Size() > maxVerifyFileSize {
+		return nil
+	}
+	data, err := os.ReadFile(hp)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(string(data), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	return lines
+}
+
+// lineAt returns 1-based line n, or "" when out of range.
+func lineAt(lines []string, n int) string {
+	if n < 1 || n > len(lines) {
+		return ""
+	}
+	return lines[n-1]
+}
+
+// sameLine compares a disk line with an index match, which the service trims
+// and shortens to 200 characters plus "...".
+func sameLine(disk, match string) bool {
+	d, m := strings.TrimSpace(disk), strings.TrimSpace(match)
+	if d == m {
+		return true
+	}
+	short, ok := strings.CutSuffix(m, "...")
+	return ok && len(short) >= 200 && strings.HasPrefix(d, short)
+}
+
+// nearestLine returns the line closest to n whose text is the match, or 0.
+func nearestLine(lines []string, n int, match string) int {
+	if strings.TrimSpace(match) == "" {
+		return 0
+	}
+	for d := 1; n-d >= 1 || n+d <= len(lines); d++ {
+		if sameLine(lineAt(lines, n-d), match) {
+			return n - d
+		}
+		if sameLine(lineAt(lines, n+d), match) {
+			return n + d
+		}
+	}
+	return 0
+}
+
+// relocate moves a hit to line n and re-reads its context from disk in both
+// the trimmed (context) and raw (before/after) shapes the service returns.
+func relocate(r GrepResult, lines []string, n, before, after int) GrepResult {
+	r.Line = n
+	if r.Before != nil || r.After != nil {
+		r.Match = lines[n-1]
+		r.Before = append([]string(nil), lines[max(n-1-before, 0):n-1]...)
+		r.After = append([]string(nil), lines[n:min(n+after, len(lines))]...)
+	}
+	if r.Context != nil {
+		c := max(before, after)
+		var ctx []string
+		for i := max(n-c, 1); i <= min(n+c, len(lines)); i++ {
+			if i == n {
+				continue
+			}
+			t := strings.TrimSpace(lines[i-1])
+			if t != "" || len(ctx) > 0 {
+				ctx = append(ctx, t)
+			}
+		}
+		for len(ctx) > 0 && ctx[len(ctx)-1] == "" {
+			ctx = ctx[:len(ctx)-1]
+		}
+		r.Context = ctx
+	}
+	return r
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVerifyGrepResults(t *testing.T) {
+	defer func(p []indexProject) { indexProjects = p }(indexProjects)
+	root := filepath.ToSlash(t.TempDir())
+	indexProjects = []indexProject{{Name: "Game", Paths: []string{root}}}
+
+	src := "#pragma once\n\nclass UAim\n{\n\tvoid Tick();\n};\n"
+	if err := os.WriteFile(filepath.Join(root, "Aim.h"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results := []GrepResult{
+		{File: "Game/Aim.h", Line: 3, Match: "class UAim"},                                   // unchanged
+		{File: "Game/Aim.h", Line: 2, Match: "void Tick();", Context: []string{"x"}},         // moved down
+		{File: "Game/Aim.h", Line: 4, Match: "void Fire();"},                                 // gone
+		{File: "Game/Gone.h", Line: 1, Match: "class UGone"},                                 // file deleted
+		{File: "/elsewhere/Aim.h", Line: 1, Match: "outside every project"},                  // not judged
+		{File: "Game/Aim.h", Line: 9, Match: "void Tick();", Before: []string{}, After: nil}, // duplicate after moving
+	}
+	got, report := verifyGrepResults(results, 1, 1, false)
+	want := []GrepResult{
+		{File: "Game/Aim.h", Line: 3, Match: "class UAim"},
+		{File: "Game/Aim.h", Line: 5, Match: "void Tick();", Context: []string{"{", "};"}},
+		{File: "Game/Aim.h", Line: 4, Match: "void Fire();", Stale: true},
+		{File: "/elsewhere/Aim.h", Line: 1, Match: "outside every project"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("verifyGrepResults =\n%#v\nwant\n%#v", got, want)
+	}
+	if want := (verifyReport{Vanished: 1, Moved: 2, Changed: 1}); report != want {
+		t.Errorf("report = %+v, want %+v", report, want)
+	}
+
+	got, _ = verifyGrepResults(results[2:3], 0, 0, true)
+	if len(got) != 0 {
+		t.Errorf("dropChanged kept %v", got)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ── Direct Zoekt fallback ────────────────────────────────────
+//
+// /grep answers 503 while Zoekt restarts, and nothing at all when the
+// service is wedged, and every Grep then goes back to the native tool. A
+// workspace with zoektPort set publishes its Zoekt webserver, so the proxy
+// sends the search to Zoekt's JSON API itself and does the service's light
+// cleanup: project-prefixed paths, trimmed and shortened lines. Hits come in
+// Zoekt's order rather than the service's ranking, and multi-word literal
+// patterns, which the service post-filters, are left to the native tool.
+
+const (
+	zoektMaxLine        = 200  // longer match and context lines are cut, as by the service
+	zoektAggregateFiles = 5000 // the service's cap for aggregate=files
+	zoektPageWindow     = 200  // documents later pages are sliced from, as in the service
+)
+
+// zoektLanguageFiles are the file: filters for the service's language names.
+var zoektLanguageFiles = map[string]string{
+	"angelscript": `\.as$`,
+	"cpp":         `\.(cpp|h|hpp|cc|inl)$`,
+	"csharp":      `\.cs$`,
+	"config":      `\.(ini|json|uplugin|uproject)$`,
+}
+
+// Zoekt's JSON encodes []byte fields as base64, which encoding/json decodes.
+type zoektLineMatch struct {
+	Line       []byte
+	LineNumber int // 0-based
+	Before     []byte
+	After      []byte
+}
+
+type zoektFileMatch struct {
+	FileName    string
+	Repository  string
+	LineMatches []zoektLineMatch
+}
+
+// File is the index path of the match: the project (Zoekt repository)
+// followed by the path inside it.
+func (f zoektFileMatch) File() string {
+	file := strings.ReplaceAll(f.FileName, "\\", "/")
+	if f.Repository != "" && !strings.HasPrefix(file, f.Repository+"/") {
+		file = f.Repository + "/" + file
+	}
+	return file
+}
+
+// zoektQuery translates /grep parameters into a Zoekt query. ok is false
+// for searches only the service can answer.
+func zoektQuery(query url.Values) (q string, ok bool) {
+	pattern := query.Get("pattern")
+	regex := hasRegexMeta(pattern)
+	if pattern == "" || (!regex && strings.Contains(pattern, " ")) {
+		return "", false
+	}
+	// Zoekt guesses case sensitivity from the pattern, so always say which
+	parts := []string{"case:yes"}
+	if query.Get("caseSensitive") == "false" {
+		parts[0] = "case:no"
+	}
+	if lang := query.Get("language"); lang != "" && lang != "all" {
+		filter, known := zoektLanguageFiles[lang]
+		if !known {
+			return "", false
+		}
+		parts = append(parts, "file:"+filter)
+	}
+	parts = append(parts, "-file:^_assets/", "-repo:^_assets$")
+	if project := query.Get("project"); project != "" {
+		parts = append(parts, "repo:^"+project+"$")
+	}
+	if regex {
+		parts = append(parts, "regex:"+pattern)
+	} else {
+		parts = append(parts, pattern)
+	}
+	return strings.Join(parts, " "), true
+}
+
+// hasRegexMeta reports whether pattern has an unescaped regex
+// metacharacter, the service's test for a regex search.
+func hasRegexMeta(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte(".+*?^${}()|[]", pattern[i]) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// zoektSearch runs q against ws's Zoekt webserver.
+func zoektSearch(ws workspaceRoute, q string, maxFiles, contextLines int) ([]zoektFileMatch, bool) {
+	if ws.ZoektPort <= 0 {
+		return nil, false
+	}
+	body := map[string]interface{}{
+		"Q": q,
+		"Opts": map[string]interface{}{
+			"MaxDocDisplayCount": maxFiles,
+			"NumContextLines":    contextLines,
+			"TotalMaxMatchCount": zoektMatchCap(maxFiles),
+			"ChunkMatches":       false,
+		},
+	}
+	var data struct {
+		Result struct {
+			Files []zoektFileMatch
+		}
+	}
+	if !postJSON(fmt.Sprintf("http://127.0.0.1:%d/api/search", ws.ZoektPort), body, &data) {
+		return nil, false
+	}
+	return data.Result.Files, true
+}
+
+// zoektGrep answers a /grep query from ws's Zoekt webserver.
+func zoektGrep(ws workspaceRoute, query url.Values) (GrepResponse, bool) {
+	q, ok := zoektQuery(query)
+	if !ok {
+		return GrepResponse{}, false
+	}
+	maxResults, err := strconv.Atoi(query.Get("maxResults"))
+	if err != nil || maxResults <= 0 {
+		maxResults = 20
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	offset = max(offset, 0)
+	contextLines, _ := strconv.Atoi(query.Get("contextLines"))
+	raw := query.Get("rawLines") == "true"
+
+	// Later pages come from one fixed window of documents, so they continue
+	// each other; the first page keeps the plain fetch, as in the service
+	pageEnd := offset + maxResults
+	fetch := maxResults
+	if offset > 0 {
+		fetch = zoektPageWindow
+	}
+	files, ok := zoektSearch(ws, q, fetch, max(contextLines, 0))
+	if !ok {
+		return GrepResponse{}, false
+	}
+	var results []GrepResult
+	for _, f := range files {
+		for _, lm := range f.LineMatches {
+			r := GrepResult{File: f.File(), Line: lm.LineNumber + 1, Match: shortLine(string(lm.Line))}
+			if raw {
+				r.Match = strings.TrimRight(string(lm.Line), "\r\n")
+				r.Before = rawLines(lm.Before)
+				r.After = rawLines(lm.After)
+			}
+			r.Context = zoektContext(lm)
+			results = append(results, r)
+		}
+	}
+	return GrepResponse{
+		Results:      results[min(offset, len(results)):min(pageEnd, len(results))],
+		TotalMatches: len(results),
+		Truncated:    len(results) > pageEnd,
+	}, true
+}
+
+// zoektGrepFiles answers an aggregate=files /grep query from ws's Zoekt
+// webserver. Files are sorted by path, as there are no mtimes to sort by.
+func zoektGrepFiles(ws workspaceRoute, query url.Values) (GrepFilesResponse, bool) {
+	q, ok := zoektQuery(query)
+	if !ok {
+		return GrepFilesResponse{}, false
+	}
+	files, ok := zoektSearch(ws, q, zoektAggregateFiles, 0)
+	if !ok {
+		return GrepFilesResponse{}, false
+	}
+	var data GrepFilesResponse
+	counts := map[string]int{}
+	for _, f := range files {
+		if len(f.LineMatches) == 0 {
+			continue
+		}
+		file := f.File()
+		if _, seen := counts[file]; !seen {
+			data.Files = append(data.Files, GrepFileCount{File: file})
+		}
+		counts[file] += len(f.LineMatches)
+		data.TotalMatches += len(f.LineMatches)
+	}
+	for i := range data.Files {
+		data.Files[i].Count = counts[data.Files[i].File]
+	}
+	sort.Slice(data.Files, func(i, j int) bool { return data.Files[i].File < data.Files[j].File })
+	// At the match cap the counts are lower bounds, as in the service
+	data.Truncated = len(files) >= zoektAggregateFiles || data.TotalMatches >= zoektMatchCap(zoektAggregateFiles)
+	return data, true
+}
+
+// zoektMatchCap is the number of line matches Zoekt collects before it
+// stops, the service's TotalMaxMatchCount.
+func zoektMatchCap(maxFiles int) int {
+	return max(maxFiles*10, 200)
+}
+
+func shortLine(s string) string {
+	s = strings.TrimSpace(s)
+	if r := []rune(s); len(r) > zoektMaxLine {
+		s = string(r[:zoektMaxLine]) + "..."
+	}
+	return s
+}
+
+// rawLines splits a Before or After blob into lines, keeping whitespace.
+func rawLines(b []byte) []string {
+	s := strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+// zoektContext is the service's trimmed context: the lines around the
+// match without leading blank lines before it or trailing ones after it.
+func zoektContext(lm zoektLineMatch) []string {
+	if len(lm.Before) == 0 && len(lm.After) == 0 {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(string(lm.Before), "\r\n", "\n"), "\n") {
+		if t := shortLine(line); t != "" || len(lines) > 0 {
+			lines = append(lines, t)
+		}
+	}
+	if len(lm.After) > 0 {
+		for _, line := range strings.Split(strings.ReplaceAll(string(lm.After), "\r\n", "\n"), "\n") {
+			lines = append(lines, shortLine(line))
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
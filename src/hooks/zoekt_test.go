@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestZoektQuery(t *testing.T) {
+	cases := []struct {
+		params url.Values
+		want   string
+		ok     bool
+	}{
+		{url.Values{"pattern": {"TickAim"}}, "case:yes -file:^_assets/ -repo:^_assets$ TickAim", true},
+		{url.Values{"pattern": {"Tick.*Aim"}, "caseSensitive": {"false"}, "language": {"cpp"}, "project": {"Game"}},
+			`case:no file:\.(cpp|h|hpp|cc|inl)$ -file:^_assets/ -repo:^_assets$ repo:^Game$ regex:Tick.*Aim`, true},
+		{url.Values{"pattern": {`Foo\.Bar`}}, `case:yes -file:^_assets/ -repo:^_assets$ Foo\.Bar`, true},
+		{url.Values{"pattern": {"class UAim"}}, "", false}, // multi-word literal: service post-filters these
+		{url.Values{"pattern": {"Aim"}, "language": {"blueprint"}}, "", false},
+	}
+	for _, c := range cases {
+		got, ok := zoektQuery(c.params)
+		if got != c.want || ok != c.ok {
+			t.Errorf("zoektQuery(%v) = %q, %v; want %q, %v", c.params, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestGrepFallsBackToZoekt(t *testing.T) {
+	defer func(p string) { breakerStatePath = p }(breakerStatePath)
+	breakerStatePath = filepath.Join(t.TempDir(), "breaker.json")
+
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"Search not available (Zoekt not running)"}`, 503)
+	}))
+	defer svc.Close()
+	var query string
+	zoekt := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Q string }
+		json.NewDecoder(r.Body).Decode(&body)
+		query = body.Q
+		json.NewEncoder(w).Encode(map[string]interface{}{"Result": map[string]interface{}{"Files": []map[string]interface{}{
+			{"FileName": "Source/Aim.cpp", "Repository": "Game", "LineMatches": []map[string]interface{}{
+				{"Line": []byte("\tvoid TickAim();\n"), "LineNumber": 9, "Before": []byte("\n// Aim\n"), "After": []byte("}\n")},
+				{"Line": []byte("TickAim(Delta);"), "LineNumber": 41},
+			}},
+			{"FileName": "Game/Script/Aim.as", "Repository": "Game", "LineMatches": []map[string]interface{}{
+				{"Line": []byte("TickAim();"), "LineNumber": 0},
+			}},
+		}}})
+	}))
+	defer zoekt.Close()
+	u, _ := url.Parse(zoekt.URL)
+	port, _ := strconv.Atoi(u.Port())
+	targets := []workspaceRoute{{URL: svc.URL, ZoektPort: port}}
+
+	p := url.Values{"pattern": {"TickAim"}, "maxResults": {"2"}, "contextLines": {"1"}}
+	data, ok := grepAll(targets, p)
+	if !ok {
+		t.Fatal("grepAll failed")
+	}
+	if query != "case:yes -file:^_assets/ -repo:^_assets$ TickAim" {
+		t.Errorf("query = %q", query)
+	}
+	want := []GrepResult{
+		{File: "Game/Source/Aim.cpp", Line: 10, Match: "void TickAim();", Context: []string{"// Aim", "", "}"}},
+		{File: "Game/Source/Aim.cpp", Line: 42, Match: "TickAim(Delta);"},
+	}
+	for i := range data.Results {
+		data.Results[i].Workspace = ""
+	}
+	if !reflect.DeepEqual(data.Results, want) || data.TotalMatches != 3 || !data.Truncated {
+		t.Errorf("got %+v", data)
+	}
+
+	files, _, _, ok := grepByFile(targets, p, func(string) bool { return true })
+	wantFiles := []GrepFileCount{{File: "Game/Script/Aim.as", Count: 1}, {File: "Game/Source/Aim.cpp", Count: 2}}
+	for i := range files {
+		files[i].Workspace = ""
+	}
+	if !ok || !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("files = %+v", files)
+	}
+
+	// Without a published Zoekt port the search goes to the native tool
+	if _, ok := grepAll([]workspaceRoute{{URL: svc.URL}}, p); ok {
+		t.Error("grepAll succeeded without zoektPort")
+	}
+
+	// A merged search names the workspace it couldn't reach
+	data, ok = grepAll([]workspaceRoute{{Name: "game", URL: svc.URL, ZoektPort: port}, {Name: "engine", URL: svc.URL}}, p)
+	if !ok || !reflect.DeepEqual(data.Unavailable, []string{"engine"}) {
+		t.Errorf("ok=%v unavailable=%v, want engine", ok, data.Unavailable)
+	}
+}
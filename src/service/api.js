@@ -7,9 +7,15 @@ import { spawn, execSync } from 'child_process';
 import { rankResults, groupResultsByFile } from './search-ranking.js';
 import { contentHash } from './trigram.js';
 import { buildWatcherCmdStartArgs } from '../watcher/watcher-launch.js';
+import { compileExcludePatterns } from '../watcher/exclude-patterns.js';
+import { freshnessMtimes, refreshEntries } from './file-refresh.js';
 
 const __dirname = dirname(fileURLToPath(import.meta.url));
 const SLOW_QUERY_MS = 100;
+const GREP_AGGREGATE_MAX_FILES = 5000;
+// Paged /grep requests (offset > 0) are sliced from one window of this many
+// Zoekt documents, ranked as a whole, so every later page continues the same order
+const GREP_PAGE_WINDOW = 200;
 
 // Read git version at startup (commit hash for version comparison)
 let SERVICE_VERSION = 'unknown';
@@ -292,6 +298,31 @@ export function createApi(database, indexer, queryPool = null, {
     return false;
   }
 
+  // Zoekt tokenizes multi-word queries, so "class Foo" matches lines with
+  // "class" OR "Foo" separately. For multi-word literal patterns, require ALL
+  // words to appear in the match line or within nearby context lines.
+  function filterMultiWord(results, pattern, caseSensitive) {
+    if (hasRegexMeta(pattern) || !pattern.includes(' ')) return results;
+    const words = pattern.split(/\s+/).filter(Boolean);
+    if (words.length <= 1) return results;
+    const needles = words.map(w => caseSensitive ? w : w.toLowerCase());
+    return results.filter(r => {
+      const hay = caseSensitive ? r.match : r.match.toLowerCase();
+      // Exact: all words on the same line
+      if (needles.every(n => hay.includes(n))) return true;
+      // Proximity: all words within match line + context lines
+      if (r.context && r.context.length > 0) {
+        const allText = [r.match, ...r.context].join(' ');
+        const allHay = caseSensitive ? allText : allText.toLowerCase();
+        if (needles.every(n => allHay.includes(n))) {
+          r._proximityMatch = true;
+          return true;
+        }
+      }
+      return false;
+    });
+  }
+
   // Execute a read query: memory index (sync) → worker pool → direct database
   async function poolQuery(method, args, timeoutMs = 30000) {
     // Try in-memory index first (synchronous, sub-millisecond)
@@ -516,6 +547,22 @@ export function createApi(database, indexer, queryPool = null, {
     }
   });
 
+  // Indexed mtimes for a handful of host paths, so the hook proxy can tell
+  // whether files edited moments ago have been ingested yet. Paths match
+  // case- and separator-insensitively; a null mtime is a file the watcher
+  // will index but hasn't yet, and files it never indexes are left out.
+  app.get('/internal/freshness', (req, res) => {
+    try {
+      const paths = [].concat(req.query.path || []).slice(0, 200);
+      const config = indexer.config;
+      const excludes = compileExcludePatterns(config?.exclude || []);
+      const stmt = database.db.prepare('SELECT mtime FROM files WHERE path_lower = ?');
+      res.json({ mtimes: freshnessMtimes(config, paths, excludes, key => stmt.get(key)) });
+    } catch (err) {
+      res.status(500).json({ error: err.message });
+    }
+  });
+
   app.get('/internal/asset-mtimes', (req, res) => {
     try {
       const { project } = req.query;
@@ -537,253 +584,280 @@ export function createApi(database, indexer, queryPool = null, {
 
   const yieldTick = () => new Promise(resolve => setImmediate(resolve));
 
-  app.post('/internal/ingest', async (req, res) => {
-    try {
-      const { files = [], assets = [], deletes = [] } = req.body;
-      const affectedProjects = new Set();
-      let processed = 0;
-      const errors = [];
+  // Apply a batch of parsed files, assets and deletes to the database, memory
+  // index and Zoekt mirror. Shared by the watcher's /internal/ingest and the
+  // hook proxy's /internal/refresh-files.
+  async function ingestBatch({ files = [], assets = [], deletes = [] }) {
+    const affectedProjects = new Set();
+    let processed = 0;
+    const errors = [];
 
-      // Process deletes
-      for (const filePath of deletes) {
-        try {
-          // Try source file first, then asset
-          if (database.deleteFile(filePath)) {
-            if (memoryIndex) memoryIndex.removeFileByPath(filePath);
-            processed++;
-          } else if (database.deleteAsset(filePath)) {
-            if (memoryIndex) memoryIndex.removeAssetByPath(filePath);
-            affectedProjects.add('_assets');
+    // Process deletes
+    for (const filePath of deletes) {
+      try {
+        // Try source file first, then asset
+        if (database.deleteFile(filePath)) {
+          if (memoryIndex) memoryIndex.removeFileByPath(filePath);
+          processed++;
+        } else if (database.deleteAsset(filePath)) {
+          if (memoryIndex) memoryIndex.removeAssetByPath(filePath);
+          affectedProjects.add('_assets');
+          processed++;
+        }
+        // Remove from mirror
+        if (zoektMirror && zoektManager) {
+          try {
+            const relativePath = zoektMirror._toRelativePath(filePath);
+            zoektManager.deleteMirrorFile(relativePath);
+          } catch {}
+        }
+      } catch (err) {
+        errors.push({ path: filePath, error: err.message });
+      }
+    }
+
+    // Process source files
+    if (files.length > 0) {
+      // Batch mtime pre-filter — single query instead of N individual lookups
+      const mtimeCheck = database.batchCheckMtimes(files.map(f => f.path));
+      let filesToProcess = [];
+      for (const file of files) {
+        const existing = mtimeCheck.get(file.path);
+        if (existing && existing.mtime === file.mtime) {
+          if (!file.content || existing.hasContent) {
             processed++;
+            continue;
           }
-          // Remove from mirror
-          if (zoektMirror && zoektManager) {
-            try {
-              const relativePath = zoektMirror._toRelativePath(filePath);
-              zoektManager.deleteMirrorFile(relativePath);
-            } catch {}
-          }
-        } catch (err) {
-          errors.push({ path: filePath, error: err.message });
         }
+        filesToProcess.push(file);
       }
 
-      // Process source files
-      if (files.length > 0) {
-        // Batch mtime pre-filter — single query instead of N individual lookups
-        const mtimeCheck = database.batchCheckMtimes(files.map(f => f.path));
-        let filesToProcess = [];
-        for (const file of files) {
-          const existing = mtimeCheck.get(file.path);
-          if (existing && existing.mtime === file.mtime) {
-            if (!file.content || existing.hasContent) {
-              processed++;
-              continue;
-            }
-          }
-          filesToProcess.push(file);
+      // Deduplicate by path (keep last occurrence) to prevent duplicate symbols
+      if (filesToProcess.length > 1) {
+        const seen = new Map();
+        for (const file of filesToProcess) {
+          seen.set(file.path, file);
+        }
+        if (seen.size < filesToProcess.length) {
+          filesToProcess = [...seen.values()];
+        }
+      }
+
+      // Opt 3: Pre-compress content outside transaction (CPU-intensive, don't hold WAL lock)
+      for (const file of filesToProcess) {
+        if (file.content && file.content.length <= 2000000) {
+          file._compressed = deflateSync(file.content);
+          file._hash = contentHash(file.content);
         }
+      }
 
-        // Deduplicate by path (keep last occurrence) to prevent duplicate symbols
-        if (filesToProcess.length > 1) {
-          const seen = new Map();
+      // Single batch transaction: upsert files → batch clear → insert types/members
+      const batchResults = [];
+      const mirrorWrites = [];
+
+      try {
+        database.transaction(() => {
+          // Phase 1: Upsert all files to get fileIds
+          const fileEntries = [];
           for (const file of filesToProcess) {
-            seen.set(file.path, file);
-          }
-          if (seen.size < filesToProcess.length) {
-            filesToProcess = [...seen.values()];
+            try {
+              const fileId = database.upsertFile(file.path, file.project, file.module, file.mtime, file.language, file.relativePath || null);
+              fileEntries.push({ fileId, file });
+            } catch (err) {
+              errors.push({ path: file.path, error: err.message });
+            }
           }
-        }
 
-        // Opt 3: Pre-compress content outside transaction (CPU-intensive, don't hold WAL lock)
-        for (const file of filesToProcess) {
-          if (file.content && file.content.length <= 2000000) {
-            file._compressed = deflateSync(file.content);
-            file._hash = contentHash(file.content);
+          // Phase 2: Batch clear old types/members/trigrams (1 call instead of N)
+          if (fileEntries.length > 0) {
+            database.clearTypesForFiles(fileEntries.map(e => e.fileId));
           }
-        }
 
-        // Single batch transaction: upsert files → batch clear → insert types/members
-        const batchResults = [];
-        const mirrorWrites = [];
+          // Phase 3: Insert new types/members and content
+          for (const { fileId, file } of fileEntries) {
+            try {
+              let insertedTypes = [];
+              if (file.types && file.types.length > 0) {
+                insertedTypes = database.insertTypes(fileId, file.types);
+              }
 
-        try {
-          database.transaction(() => {
-            // Phase 1: Upsert all files to get fileIds
-            const fileEntries = [];
-            for (const file of filesToProcess) {
-              try {
-                const fileId = database.upsertFile(file.path, file.project, file.module, file.mtime, file.language, file.relativePath || null);
-                fileEntries.push({ fileId, file });
-              } catch (err) {
-                errors.push({ path: file.path, error: err.message });
+              let insertedMembers = [];
+              if (file.members && file.members.length > 0) {
+                const nameToId = new Map(insertedTypes.map(t => [t.name, t.id]));
+                const resolvedMembers = file.members.map(m => ({
+                  typeId: nameToId.get(m.ownerName) || null,
+                  name: m.name,
+                  memberKind: m.memberKind,
+                  line: m.line,
+                  isStatic: m.isStatic,
+                  specifiers: m.specifiers
+                }));
+                insertedMembers = database.insertMembers(fileId, resolvedMembers);
               }
-            }
 
-            // Phase 2: Batch clear old types/members/trigrams (1 call instead of N)
-            if (fileEntries.length > 0) {
-              database.clearTypesForFiles(fileEntries.map(e => e.fileId));
-            }
+              if (file._compressed) {
+                database.upsertFileContent(fileId, file._compressed, file._hash);
+              }
 
-            // Phase 3: Insert new types/members and content
-            for (const { fileId, file } of fileEntries) {
-              try {
-                let insertedTypes = [];
-                if (file.types && file.types.length > 0) {
-                  insertedTypes = database.insertTypes(fileId, file.types);
-                }
-
-                let insertedMembers = [];
-                if (file.members && file.members.length > 0) {
-                  const nameToId = new Map(insertedTypes.map(t => [t.name, t.id]));
-                  const resolvedMembers = file.members.map(m => ({
-                    typeId: nameToId.get(m.ownerName) || null,
-                    name: m.name,
-                    memberKind: m.memberKind,
-                    line: m.line,
-                    isStatic: m.isStatic,
-                    specifiers: m.specifiers
-                  }));
-                  insertedMembers = database.insertMembers(fileId, resolvedMembers);
-                }
-
-                if (file._compressed) {
-                  database.upsertFileContent(fileId, file._compressed, file._hash);
-                }
-
-                batchResults.push({ fileId, file, insertedTypes, insertedMembers });
-
-                // Collect mirror write for after transaction
-                if (file.content && file.content.length <= 2000000 && zoektManager) {
-                  const mirrorPath = file.relativePath
-                    ? `${file.project}/${file.relativePath}`
-                    : (zoektMirror ? zoektMirror._toRelativePath(file.path) : file.path);
-                  mirrorWrites.push({ path: mirrorPath, content: file.content });
-                }
-              } catch (err) {
-                errors.push({ path: file.path, error: err.message });
+              batchResults.push({ fileId, file, insertedTypes, insertedMembers });
+
+              // Collect mirror write for after transaction
+              if (file.content && file.content.length <= 2000000 && zoektManager) {
+                const mirrorPath = file.relativePath
+                  ? `${file.project}/${file.relativePath}`
+                  : (zoektMirror ? zoektMirror._toRelativePath(file.path) : file.path);
+                mirrorWrites.push({ path: mirrorPath, content: file.content });
               }
+            } catch (err) {
+              errors.push({ path: file.path, error: err.message });
             }
-          });
-        } catch (err) {
-          errors.push({ type: 'batch-transaction', error: err.message });
-        }
+          }
+        });
+      } catch (err) {
+        errors.push({ type: 'batch-transaction', error: err.message });
+      }
 
-        // Yield after transaction to keep event loop responsive
-        await yieldTick();
+      // Yield after transaction to keep event loop responsive
+      await yieldTick();
 
-        // Perform mirror writes outside transaction
-        for (const { path: mirrorPath, content } of mirrorWrites) {
-          try {
-            zoektManager.updateMirrorFile(mirrorPath, content);
-          } catch {}
-        }
+      // Perform mirror writes outside transaction
+      for (const { path: mirrorPath, content } of mirrorWrites) {
+        try {
+          zoektManager.updateMirrorFile(mirrorPath, content);
+        } catch {}
+      }
 
-        // Yield after mirror writes
-        if (mirrorWrites.length > 0) await yieldTick();
-
-        // Sync memory index using captured insert data (no DB round-trip)
-        for (const { fileId, file, insertedTypes, insertedMembers } of batchResults) {
-          if (memoryIndex) {
-            memoryIndex.removeFile(fileId);
-            const baseLower = file.path.replace(/\\/g, '/');
-            const lastSlash = baseLower.lastIndexOf('/');
-            const fn = lastSlash >= 0 ? baseLower.substring(lastSlash + 1) : baseLower;
-            const dotIdx = fn.lastIndexOf('.');
-            const stem = dotIdx > 0 ? fn.substring(0, dotIdx) : fn;
-            memoryIndex.addFile(fileId, {
-              path: file.path, project: file.project, module: file.module,
-              language: file.language, mtime: file.mtime,
-              basenameLower: stem.toLowerCase(),
-              relativePath: file.relativePath || null
-            });
+      // Yield after mirror writes
+      if (mirrorWrites.length > 0) await yieldTick();
+
+      // Sync memory index using captured insert data (no DB round-trip)
+      for (const { fileId, file, insertedTypes, insertedMembers } of batchResults) {
+        if (memoryIndex) {
+          memoryIndex.removeFile(fileId);
+          const baseLower = file.path.replace(/\\/g, '/');
+          const lastSlash = baseLower.lastIndexOf('/');
+          const fn = lastSlash >= 0 ? baseLower.substring(lastSlash + 1) : baseLower;
+          const dotIdx = fn.lastIndexOf('.');
+          const stem = dotIdx > 0 ? fn.substring(0, dotIdx) : fn;
+          memoryIndex.addFile(fileId, {
+            path: file.path, project: file.project, module: file.module,
+            language: file.language, mtime: file.mtime,
+            basenameLower: stem.toLowerCase(),
+            relativePath: file.relativePath || null
+          });
 
-            if (insertedTypes.length > 0) {
-              memoryIndex.addTypes(fileId, insertedTypes);
-            }
-            if (insertedMembers.length > 0) {
-              memoryIndex.addMembers(fileId, insertedMembers);
-            }
+          if (insertedTypes.length > 0) {
+            memoryIndex.addTypes(fileId, insertedTypes);
+          }
+          if (insertedMembers.length > 0) {
+            memoryIndex.addMembers(fileId, insertedMembers);
           }
-
-          affectedProjects.add(file.project);
-          processed++;
         }
 
-        // Free pre-compressed data
-        for (const file of filesToProcess) {
-          delete file._compressed;
-          delete file._hash;
-        }
+        affectedProjects.add(file.project);
+        processed++;
+      }
 
-        // Yield after memory index sync
-        if (batchResults.length > 0) await yieldTick();
+      // Free pre-compressed data
+      for (const file of filesToProcess) {
+        delete file._compressed;
+        delete file._hash;
       }
 
-      // Process assets
-      if (assets.length > 0) {
-        try {
-          const assetDbRows = database.upsertAssetBatch(assets);
-          database.indexAssetContent(assets);
-
-          // Sync memory index using batch-returned rows (no individual SELECTs)
-          if (memoryIndex && assetDbRows.length > 0) {
-            memoryIndex.upsertAssets(assetDbRows.map(r => ({
-              id: r.id, path: r.path, name: r.name, contentPath: r.content_path,
-              folder: r.folder, project: r.project, extension: r.extension, mtime: r.mtime,
-              assetClass: r.asset_class, parentClass: r.parent_class
-            })));
-          }
+      // Yield after memory index sync
+      if (batchResults.length > 0) await yieldTick();
+    }
 
-          affectedProjects.add('_assets');
-          processed += assets.length;
-        } catch (err) {
-          errors.push({ type: 'assets', error: err.message });
+    // Process assets
+    if (assets.length > 0) {
+      try {
+        const assetDbRows = database.upsertAssetBatch(assets);
+        database.indexAssetContent(assets);
+
+        // Sync memory index using batch-returned rows (no individual SELECTs)
+        if (memoryIndex && assetDbRows.length > 0) {
+          memoryIndex.upsertAssets(assetDbRows.map(r => ({
+            id: r.id, path: r.path, name: r.name, contentPath: r.content_path,
+            folder: r.folder, project: r.project, extension: r.extension, mtime: r.mtime,
+            assetClass: r.asset_class, parentClass: r.parent_class
+          })));
         }
-      }
 
-      // Flag inheritance depth for recomputation after new types are ingested
-      if (processed > 0) {
-        database.setMetadata('depthComputeNeeded', true);
-        scheduleDepthRecompute();
-        grepCache.invalidate();
-        if (memoryIndex) memoryIndex.invalidateInheritanceCache();
+        affectedProjects.add('_assets');
+        processed += assets.length;
+      } catch (err) {
+        errors.push({ type: 'assets', error: err.message });
       }
+    }
 
-      // Track ingest activity for watcher status
-      watcherState.lastIngestAt = new Date().toISOString();
-      watcherState.ingestCounts.total += processed;
-      watcherState.ingestCounts.files += files.length;
-      watcherState.ingestCounts.assets += assets.length;
-      watcherState.ingestCounts.deletes += deletes.length;
+    // Flag inheritance depth for recomputation after new types are ingested
+    if (processed > 0) {
+      database.setMetadata('depthComputeNeeded', true);
+      scheduleDepthRecompute();
+      grepCache.invalidate();
+      if (memoryIndex) memoryIndex.invalidateInheritanceCache();
+    }
 
-      // Trigger Zoekt reindex for affected projects
-      if (zoektManager && affectedProjects.size > 0) {
-        zoektManager.triggerReindex(processed, affectedProjects);
-      }
+    // Track ingest activity for watcher status
+    watcherState.lastIngestAt = new Date().toISOString();
+    watcherState.ingestCounts.total += processed;
+    watcherState.ingestCounts.files += files.length;
+    watcherState.ingestCounts.assets += assets.length;
+    watcherState.ingestCounts.deletes += deletes.length;
 
-      // Update mirror marker so bootstrapFromDatabase doesn't run on restart
-      if (zoektMirror && processed > 0) {
-        try {
-          const fileCount = database.db.prepare(
-            "SELECT COUNT(*) as c FROM file_content"
-          ).get().c;
-          if (zoektMirror.markerPath) {
-            writeFileSync(zoektMirror.markerPath, JSON.stringify({
-              timestamp: new Date().toISOString(),
-              fileCount,
-              source: 'ingest'
-            }));
-          }
-        } catch {}
-      }
+    // Trigger Zoekt reindex for affected projects
+    if (zoektManager && affectedProjects.size > 0) {
+      zoektManager.triggerReindex(processed, affectedProjects);
+    }
 
+    // Update mirror marker so bootstrapFromDatabase doesn't run on restart
+    if (zoektMirror && processed > 0) {
+      try {
+        const fileCount = database.db.prepare(
+          "SELECT COUNT(*) as c FROM file_content"
+        ).get().c;
+        if (zoektMirror.markerPath) {
+          writeFileSync(zoektMirror.markerPath, JSON.stringify({
+            timestamp: new Date().toISOString(),
+            fileCount,
+            source: 'ingest'
+          }));
+        }
+      } catch {}
+    }
+
+    return { processed, errors };
+  }
+
+  app.post('/internal/ingest', async (req, res) => {
+    try {
+      const { processed, errors } = await ingestBatch(req.body);
       res.json({ processed, errors: errors.length > 0 ? errors : undefined });
     } catch (err) {
       res.status(500).json({ error: err.message });
     }
   });
 
+  // Re-ingest specific files right after the agent edits them. The hook proxy
+  // sends { files: [{ path, mtime, content }] }; files the watcher doesn't
+  // index (outside every source project, not collected by its filters, or
+  // excluded) are skipped.
+  app.post('/internal/refresh-files', async (req, res) => {
+    try {
+      const config = indexer.config;
+      if (!config) {
+        return res.status(500).json({ error: 'Config not loaded' });
+      }
+      const excludes = compileExcludePatterns(config.exclude || []);
+      const storedPath = database.db.prepare('SELECT path FROM files WHERE path_lower = ?');
+      const { entries, skipped } = refreshEntries(config, req.body?.files || [], excludes, key => storedPath.get(key));
+      const { processed, errors } = entries.length > 0 ? await ingestBatch({ files: entries }) : { processed: 0, errors: [] };
+      res.json({ processed, skipped, errors: errors.length > 0 ? errors : undefined });
+    } catch (err) {
+      res.status(500).json({ error: err.message });
+    }
+  });
+
   app.post('/internal/heartbeat', (req, res) => {
     const hb = req.body;
     if (!hb || !hb.watcherId) {
@@ -1124,7 +1198,7 @@ export function createApi(database, indexer, queryPool = null, {
 
   app.get('/browse-module', async (req, res) => {
     try {
-      const { module, project: rawProject, language, maxResults } = req.query;
+      const { module, project: rawProject, language, maxResults, filesOnly } = req.query;
 
       if (!module) {
         return res.status(400).json({ error: 'module parameter required' });
@@ -1134,7 +1208,8 @@ export function createApi(database, indexer, queryPool = null, {
       const opts = {
         project,
         language: language || null,
-        maxResults: parseInt(maxResults, 10) || 100
+        maxResults: parseInt(maxResults, 10) || 100,
+        filesOnly: filesOnly === 'true'
       };
 
       const result = await poolQuery('browseModule', [module, opts]);
@@ -1604,6 +1679,8 @@ export function createApi(database, indexer, queryPool = null, {
               result.forEach(r => {
                 if (r.path) r.path = cleanPath(r.path, r.project);
                 if (r.implementationPath) r.implementationPath = cleanPath(r.implementationPath, r.project);
+                // findFileByName results carry the path in .file, as in /find-file
+                if (r.file) r.file = cleanPath(r.file, r.project);
               });
             }
           } else if (result && result.results && BATCH_PATH_METHODS.has(method)) {
@@ -1629,7 +1706,7 @@ export function createApi(database, indexer, queryPool = null, {
   // --- Content search (grep) ---
 
   app.get('/grep', async (req, res) => {
-    const { pattern, project: rawProject, language, caseSensitive: cs, maxResults: mr, contextLines: cl, grouped, includeAssets: ia, symbols: sym } = req.query;
+    const { pattern, project: rawProject, language, caseSensitive: cs, maxResults: mr, contextLines: cl, grouped, includeAssets: ia, symbols: sym, aggregate, offset: off, rawLines: raw } = req.query;
 
     if (!pattern) {
       return res.status(400).json({ error: 'pattern parameter required' });
@@ -1641,6 +1718,7 @@ export function createApi(database, indexer, queryPool = null, {
 
     const caseSensitive = cs !== 'false';
     const maxResults = parseInt(mr, 10) || 20;
+    const offset = Math.max(parseInt(off, 10) || 0, 0);
     const contextLines = cl !== undefined ? parseInt(cl, 10) : 0;
     const includeAssets = ia === 'true';
     const skipSymbols = sym === 'false';
@@ -1648,7 +1726,7 @@ export function createApi(database, indexer, queryPool = null, {
     const { project, projectWarning } = validateProject(database, rawProject, memoryIndex);
 
     // Check grep cache (uses validated project so unknown projects map to all-project cache)
-    const cacheKey = `${pattern}|${project || ''}|${language || ''}|${cs}|${mr}|${cl}|${grouped}|${ia}|${sym}`;
+    const cacheKey = `${pattern}|${project || ''}|${language || ''}|${cs}|${mr}|${cl}|${grouped}|${ia}|${sym}|${aggregate || ''}|${offset}|${raw}`;
     const cached = grepCache.get(cacheKey);
     if (cached) {
       if (projectWarning) {
@@ -1669,9 +1747,64 @@ export function createApi(database, indexer, queryPool = null, {
 
     const grepStartMs = performance.now();
 
+    // aggregate=files: per-file matching-line counts over the whole corpus
+    // (count / files_with_matches), newest files first like native Grep
+    if (aggregate === 'files') {
+      try {
+        const isMultiWord = !hasRegexMeta(pattern) && pattern.includes(' ');
+        const sourceResult = await zoektClient.search(pattern, {
+          project,
+          language: (language && language !== 'all') ? language : null,
+          caseSensitive,
+          maxResults: GREP_AGGREGATE_MAX_FILES,
+          contextLines: isMultiWord ? 3 : 0
+        });
+        const results = filterMultiWord(sourceResult.results, pattern, caseSensitive);
+        const counts = new Map();
+        for (const r of results) {
+          const file = cleanPath(r.file);
+          counts.set(file, (counts.get(file) || 0) + 1);
+        }
+        const paths = [...counts.keys()];
+        const mtimeMap = (memoryIndex?.isLoaded)
+          ? memoryIndex.getFilesMtime(paths)
+          : database.getFilesMtime(paths);
+        const files = paths
+          .map(file => ({ file, count: counts.get(file) }))
+          .sort((a, b) => (mtimeMap.get(b.file) || 0) - (mtimeMap.get(a.file) || 0) || a.file.localeCompare(b.file));
+        const durationMs = Math.round(performance.now() - grepStartMs);
+        console.log(`[Grep] "${pattern.slice(0, 60)}" -> ${files.length} files aggregated (${durationMs}ms)`);
+
+        const response = {
+          files,
+          totalMatches: results.length,
+          matchedFiles: files.length,
+          // Zoekt stops at its display/match caps; more files may match, and at
+          // the match cap the counts are lower bounds. Multi-word post-filtering
+          // always drops files, so only the caps apply there.
+          truncated: files.length >= GREP_AGGREGATE_MAX_FILES || sourceResult.matchCapReached ||
+            (!isMultiWord && sourceResult.matchedFiles > files.length),
+          zoektDurationMs: sourceResult.zoektDurationMs
+        };
+        grepCache.set(cacheKey, response);
+        if (projectWarning) {
+          return res.json({ ...response, hints: [projectWarning] });
+        }
+        return res.json(response);
+      } catch (err) {
+        console.warn(`[Grep] "${pattern.slice(0, 60)}" -> aggregate error: ${err.message}`);
+        return res.status(500).json({ error: err.message });
+      }
+    }
+
     // Over-fetch from Zoekt when multi-word post-filter will discard many results
     const isMultiWord = !hasRegexMeta(pattern) && pattern.includes(' ');
-    const zoektMaxResults = isMultiWord ? Math.max(maxResults * 5, 100) : maxResults;
+    // Paging: later pages are sliced after ranking from a window whose size
+    // doesn't depend on the offset, so they never repeat or skip hits. The
+    // first page keeps the plain fetch, so callers that don't page pay nothing.
+    const pageEnd = offset + maxResults;
+    const fetchCount = offset > 0 ? GREP_PAGE_WINDOW : maxResults;
+    const zoektMaxResults = isMultiWord ? Math.max(fetchCount * 5, 100) : fetchCount;
     // Request context lines for proximity matching on multi-word queries
     const effectiveContextLines = isMultiWord ? Math.max(contextLines, 3) : contextLines;
 
@@ -1683,7 +1816,8 @@ export function createApi(database, indexer, queryPool = null, {
         language: (language && language !== 'all') ? language : null,
         caseSensitive,
         maxResults: zoektMaxResults,
-        contextLines: effectiveContextLines
+        contextLines: effectiveContextLines,
+        rawLines: raw === 'true'
       });
       const assetPromise = includeAssets
         ? zoektClient.searchAssets(pattern, { project, caseSensitive, maxResults: 20 })
@@ -1694,30 +1828,8 @@ export function createApi(database, indexer, queryPool = null, {
       // Clean paths and rank results
       let results = sourceResult.results.map(r => ({ ...r, file: cleanPath(r.file) }));
 
-      // Post-filter: Zoekt tokenizes multi-word queries, so "class Foo" matches
-      // lines with "class" OR "Foo" separately. For multi-word literal patterns,
-      // require ALL words to appear in the match line or within nearby context lines.
-      if (!hasRegexMeta(pattern) && pattern.includes(' ')) {
-        const words = pattern.split(/\s+/).filter(Boolean);
-        if (words.length > 1) {
-          const needles = words.map(w => caseSensitive ? w : w.toLowerCase());
-          results = results.filter(r => {
-            const hay = caseSensitive ? r.match : r.match.toLowerCase();
-            // Exact: all words on the same line
-            if (needles.every(n => hay.includes(n))) return true;
-            // Proximity: all words within match line + context lines
-            if (r.context && r.context.length > 0) {
-              const allText = [r.match, ...r.context].join(' ');
-              const allHay = caseSensitive ? allText : allText.toLowerCase();
-              if (needles.every(n => allHay.includes(n))) {
-                r._proximityMatch = true;
-                return true;
-              }
-            }
-            return false;
-          });
-        }
-      }
+      // Post-filter: multi-word literal patterns need every word nearby
+      results = filterMultiWord(results, pattern, caseSensitive);
 
       const postFilterCount = results.length;
 
@@ -1739,8 +1851,7 @@ export function createApi(database, indexer, queryPool = null, {
       }
       const tEnrich = performance.now();
 
-      results = rankResults(results, mtimeMap, symbolMap);
-      if (results.length > maxResults) results = results.slice(0, maxResults);
+      results = rankResults(results, mtimeMap, symbolMap).slice(offset, pageEnd);
       const tRank = performance.now();
 
       const ms = v => v.toFixed(1);
@@ -1766,13 +1877,16 @@ export function createApi(database, indexer, queryPool = null, {
           grepHints.push(`No results in project '${project}'. Try removing the project filter to search all projects.`);
         }
       }
+      if (offset > 0 && postFilterCount <= pageEnd && sourceResult.matchedFiles > zoektMaxResults) {
+        grepHints.push(`Paging stops after the first ${zoektMaxResults} matching files. Narrow the pattern or add a project or language filter to see the rest.`);
+      }
 
       if (grouped !== 'false') {
         const groupedResponse = {
           results: groupResultsByFile(results),
           totalMatches: postFilterCount,
           matchedFiles: sourceResult.matchedFiles,
-          truncated: postFilterCount > maxResults,
+          truncated: postFilterCount > pageEnd,
           grouped: true,
           zoektDurationMs: sourceResult.zoektDurationMs
         };
@@ -1789,7 +1903,7 @@ export function createApi(database, indexer, queryPool = null, {
         results,
         totalMatches: postFilterCount,
         matchedFiles: sourceResult.matchedFiles,
-        truncated: postFilterCount > maxResults,
+        truncated: postFilterCount > pageEnd,
         zoektDurationMs: sourceResult.zoektDurationMs
       };
       if (assetResult.results.length > 0) {
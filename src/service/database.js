@@ -1469,7 +1469,9 @@ export class IndexDatabase {
   }
 
   browseModule(modulePath, options = {}) {
-    const { project = null, language = null, maxResults = 100 } = options;
+    const { project = null, language = null, maxResults = 100, filesOnly = false } = options;
+
+    if (filesOnly) return this._browseModuleFiles(modulePath, { project, language, maxResults });
 
     let sql = `
       SELECT t.name, t.kind, t.parent, t.line, f.path, f.project, f.module
@@ -1499,20 +1501,7 @@ export class IndexDatabase {
 
     // If no types found (e.g., config files have no types), list files directly
     if (results.length === 0) {
-      let filesSql = "SELECT path, project, language FROM files WHERE (module = ? OR module LIKE ?) AND language != 'asset'";
-      const filesParams = [modulePath, `${modulePath}.%`];
-      if (project) { filesSql += ' AND project = ?'; filesParams.push(project); }
-      if (language && language !== 'all') { filesSql += ' AND language = ?'; filesParams.push(language); }
-      filesSql += ' LIMIT ?';
-      filesParams.push(maxResults + 1);
-      const fileResults = this.db.prepare(filesSql).all(...filesParams);
-      return {
-        module: modulePath,
-        types: [],
-        files: fileResults.slice(0, maxResults).map(f => f.path),
-        truncated: fileResults.length > maxResults,
-        totalFiles: fileResults.length
-      };
+      return this._browseModuleFiles(modulePath, { project, language, maxResults });
     }
 
     return {
@@ -1524,6 +1513,24 @@ export class IndexDatabase {
     };
   }
 
+  /** List every file in a module subtree (no types), up to maxResults. */
+  _browseModuleFiles(modulePath, { project, language, maxResults }) {
+    let filesSql = "SELECT path, project, language FROM files WHERE (module = ? OR module LIKE ?) AND language != 'asset'";
+    const filesParams = [modulePath, `${modulePath}.%`];
+    if (project) { filesSql += ' AND project = ?'; filesParams.push(project); }
+    if (language && language !== 'all') { filesSql += ' AND language = ?'; filesParams.push(language); }
+    filesSql += ' LIMIT ?';
+    filesParams.push(maxResults + 1);
+    const fileResults = this.db.prepare(filesSql).all(...filesParams);
+    return {
+      module: modulePath,
+      types: [],
+      files: fileResults.slice(0, maxResults).map(f => f.path),
+      truncated: fileResults.length > maxResults,
+      totalFiles: fileResults.length
+    };
+  }
+
   findFileByName(filename, options = {}) {
     const { project = null, language = null, maxResults = 20 } = options;
     const filenameLower = filename.toLowerCase().replace(/\.[^.]+$/, '');
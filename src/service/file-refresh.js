@@ -0,0 +1,106 @@
+import { posix, win32 } from 'path';
+import { shouldExcludePath } from '../watcher/exclude-patterns.js';
+import { buildSourceEntry, matchesSourceFile, projectExtensions } from '../watcher/source-entry.js';
+
+// Per-file refresh for files the agent just edited. The hook proxy runs next
+// to the files and pushes their content, so the service can re-ingest them
+// without waiting for the watcher (and without reading the host filesystem).
+
+// WSL mounts (/mnt/d/...) compare equal to the drive paths (d:/...) the
+// Windows watcher and config use
+function normalize(p) {
+  return p.replace(/\\/g, '/').replace(/\/+$/, '').toLowerCase().replace(/^\/mnt\/([a-z])(?=\/|$)/, '$1:');
+}
+
+/** Keys to look up a host path in files.path_lower, as sent and as a drive path. */
+export function pathLowerKeys(p) {
+  const lower = p.replace(/\\/g, '/').toLowerCase();
+  const drive = normalize(p);
+  return lower === drive ? [lower] : [lower, drive];
+}
+
+/**
+ * Find the source project whose watcher indexes filePath: the most specific
+ * base path containing it whose project collects the file, by extension and
+ * includePatterns, unless an exclude pattern matches the watcher's spelling
+ * of the path. Returns { project, basePath } or null.
+ */
+export function findProjectForFile(config, filePath, excludes) {
+  const norm = normalize(filePath);
+  let best = null;
+  for (const project of config?.projects || []) {
+    if (project.language === 'content') continue;
+    if (!matchesSourceFile(filePath, projectExtensions(project), project.includePatterns)) continue;
+    for (const basePath of project.paths || []) {
+      const base = normalize(basePath);
+      if (!norm.startsWith(base + '/')) continue;
+      if (!best || base.length > normalize(best.basePath).length) {
+        best = { project, basePath };
+      }
+    }
+  }
+  if (best && shouldExcludePath(watcherPath(best, filePath), excludes)) return null;
+  return best;
+}
+
+// Only the drive or mount head differs between spellings, so the tail is exact
+function relativeTail(basePath, filePath) {
+  const relLength = normalize(filePath).length - normalize(basePath).length - 1;
+  return filePath.replace(/\\/g, '/').slice(-relLength);
+}
+
+/**
+ * The path the watcher would store for filePath: the config base path joined
+ * with the relative path, as the watcher's path.join on that platform writes it.
+ */
+export function watcherPath({ basePath }, filePath) {
+  const join = /^[a-z]:/i.test(basePath) ? win32.join : posix.join;
+  return join(basePath, relativeTail(basePath, filePath));
+}
+
+/**
+ * Build an /internal/ingest file entry, parsed as the watcher parses it.
+ */
+export function buildIngestEntry({ project, basePath }, filePath, mtime, content) {
+  const relativePath = relativeTail(basePath, filePath);
+  return buildSourceEntry({ path: filePath, project, language: project.language, relativePath, mtime, content });
+}
+
+/**
+ * Ingest entries for the files the hook proxy pushed ({ path, mtime, content }).
+ * Files the watcher doesn't index are skipped. A stored row keeps its path
+ * spelling and new files take the watcher's, so a file is never indexed
+ * twice; storedRow(key) looks a files.path_lower key up.
+ */
+export function refreshEntries(config, files, excludes, storedRow) {
+  const entries = [];
+  const skipped = [];
+  for (const { path, mtime, content } of files) {
+    const target = typeof path === 'string' && typeof content === 'string' && findProjectForFile(config, path, excludes);
+    if (!target) {
+      skipped.push(path);
+      continue;
+    }
+    const existing = pathLowerKeys(path).map(storedRow).find(Boolean);
+    entries.push(buildIngestEntry(target, existing ? existing.path : watcherPath(target, path), Math.floor(mtime || Date.now()), content));
+  }
+  return { entries, skipped };
+}
+
+/**
+ * Indexed mtimes for host paths, for /internal/freshness. Files the watcher
+ * doesn't index are left out; those it does map to the stored row's mtime,
+ * looked up as sent and in the watcher's spelling, or to null when it hasn't
+ * ingested them yet. storedRow(key) looks a files.path_lower key up.
+ */
+export function freshnessMtimes(config, paths, excludes, storedRow) {
+  const mtimes = {};
+  for (const p of paths) {
+    const target = typeof p === 'string' && findProjectForFile(config, p, excludes);
+    if (!target) continue;
+    const keys = new Set([...pathLowerKeys(p), ...pathLowerKeys(watcherPath(target, p))]);
+    const row = [...keys].map(storedRow).find(Boolean);
+    mtimes[p] = row ? row.mtime : null;
+  }
+  return mtimes;
+}
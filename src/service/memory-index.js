@@ -1283,13 +1283,13 @@ export class MemoryIndex {
   }
 
   browseModule(modulePath, options = {}) {
-    const { project = null, language = null, maxResults = 100 } = options;
+    const { project = null, language = null, maxResults = 100, filesOnly = false } = options;
 
     const results = [];
     const fileSet = new Set();
 
-    // Gather types from matching modules
-    for (const [mod, fileIds] of this.filesByModule) {
+    // Gather types from matching modules (skipped when only the file listing is wanted)
+    for (const [mod, fileIds] of filesOnly ? [] : this.filesByModule) {
       if (mod !== modulePath && !mod.startsWith(modulePath + '.')) continue;
 
       for (const fid of fileIds) {
@@ -98,7 +98,8 @@ export function rankResults(results, mtimeMap, symbolMap = null) {
       + (isDefinitionLine(b.match) ? 8 : 0)
       + (symbolB ? 6 : 0)
       + (b._proximityMatch ? -3 : 0);
-    return sb - sa || a.line - b.line;
+    // Ties break by file and line so the order (and so paging) is stable
+    return sb - sa || a.file.localeCompare(b.file) || a.line - b.line;
   });
 
   // Strip transient fields before returning
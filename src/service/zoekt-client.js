@@ -26,12 +26,12 @@ export class ZoektClient {
   }
 
   async search(pattern, options = {}) {
-    const { project, language, caseSensitive = true, maxResults = 50, contextLines = 2 } = options;
+    const { project, language, caseSensitive = true, maxResults = 50, contextLines = 2, rawLines = false } = options;
 
     // Source query: exclude _assets/ paths to avoid mixing with asset results
     const query = this._buildQuery(pattern, { project, language, caseSensitive, excludeAssets: true });
 
-    return this._executeQuery(query, maxResults, contextLines);
+    return this._executeQuery(query, maxResults, contextLines, { rawLines });
   }
 
   async searchAssets(pattern, options = {}) {
@@ -103,7 +103,7 @@ export class ZoektClient {
     return this._executeQuery(parts.join(' '), maxResults, 0);
   }
 
-  async _executeQuery(query, maxResults, contextLines) {
+  async _executeQuery(query, maxResults, contextLines, { rawLines = false } = {}) {
     const body = {
       Q: query,
       Opts: {
@@ -144,7 +144,10 @@ export class ZoektClient {
 
         const data = await resp.json();
         const durationMs = performance.now() - startMs;
-        return this._mapResponse(data, durationMs);
+        const result = this._mapResponse(data, durationMs, { rawLines });
+        // Zoekt stops collecting at the match cap, so per-file counts may be short
+        result.matchCapReached = result.results.length >= body.Opts.TotalMaxMatchCount;
+        return result;
       } catch (err) {
         clearTimeout(timeout);
 
@@ -221,7 +224,16 @@ export class ZoektClient {
     return Buffer.from(val, 'base64').toString('utf-8');
   }
 
-  _mapResponse(data, durationMs) {
+  // Split a Before/After context blob into lines, keeping whitespace
+  _splitRawLines(val) {
+    const text = this._decodeBytes(val);
+    if (!text) return [];
+    return text.replace(/\r?\n$/, '').split(/\r?\n/);
+  }
+
+  // rawLines: keep match lines untrimmed and unshortened, and return context as
+  // separate before/after arrays (for output that mirrors ripgrep exactly)
+  _mapResponse(data, durationMs, { rawLines = false } = {}) {
     const MAX_LINE = 200;
     const results = [];
     let totalMatches = 0;
@@ -256,6 +268,11 @@ export class ZoektClient {
           line: (lm.LineNumber || 0) + 1, // Zoekt uses 0-based line numbers
           match
         };
+        if (rawLines) {
+          result.match = this._decodeBytes(lm.Line).replace(/\r?\n$/, '');
+          result.before = this._splitRawLines(lm.Before);
+          result.after = this._splitRawLines(lm.After);
+        }
 
         // Context lines (Before/After are []byte → single base64 string containing multiple lines)
         if (lm.Before || lm.After) {
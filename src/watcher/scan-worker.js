@@ -5,11 +5,9 @@ import { readFile, stat } from 'fs/promises';
 import { readdirSync, statSync } from 'fs';
 import { join, relative } from 'path';
 import { gzipSync } from 'zlib';
-import { parseContent as parseAngelscriptContent } from '../parsers/angelscript-parser.js';
-import { parseCppContent } from '../parsers/cpp-parser.js';
-import { parseCSharpContent } from '../parsers/csharp-parser.js';
 import { parseUAssetHeader } from '../parsers/uasset-parser.js';
 import { compileExcludePatterns, shouldExcludePath } from './exclude-patterns.js';
+import { buildSourceEntry, deriveModule, matchesSourceFile, projectExtensions } from './source-entry.js';
 
 const MAX_CONCURRENT = 10;
 const BATCH_SIZE = 50;
@@ -77,12 +75,6 @@ function findBasePathForFile(filePath, project) {
   return null;
 }
 
-function deriveModule(relativePath, projectName) {
-  const parts = relativePath.replace(/\.(as|h|cpp|cs)$/, '').split('/');
-  parts.pop();
-  return [projectName, ...parts].join('.');
-}
-
 export function shouldExclude(filePath, excludeMatchers = compiledExcludePatterns) {
   const matchers = Array.isArray(excludeMatchers) && typeof excludeMatchers[0] === 'string'
     ? compileExcludePatterns(excludeMatchers)
@@ -106,10 +98,7 @@ function collectFiles(dirPath, projectName, extensions, language, includePattern
         if (shouldExclude(fullPath)) continue;
         scanDir(fullPath);
       } else if (entry.isFile()) {
-        if (!extensions.some(ext => entry.name.endsWith(ext))) continue;
-        if (includePatterns?.length > 0) {
-          if (!includePatterns.some(pat => pat.startsWith('*') ? entry.name.endsWith(pat.slice(1)) : entry.name === pat)) continue;
-        }
+        if (!matchesSourceFile(entry.name, extensions, includePatterns)) continue;
         if (shouldExclude(fullPath)) continue;
         try {
           const mtime = Math.floor(statSync(fullPath).mtimeMs);
@@ -198,47 +187,8 @@ async function readAndParseSource(filePath, project, language) {
   const fileStat = await stat(filePath);
   const mtime = Math.floor(fileStat.mtimeMs);
   const relativePath = relative(basePath, filePath).replace(/\\/g, '/');
-  const module = deriveModule(relativePath, project.name);
-
-  if (language === 'config') {
-    const content = await readFile(filePath, 'utf-8');
-    return { path: filePath, project: project.name, module, mtime, language, relativePath, content, types: [], members: [] };
-  }
-
   const content = await readFile(filePath, 'utf-8');
-  let parsed;
-  if (language === 'cpp') {
-    parsed = parseCppContent(content, filePath);
-  } else if (language === 'csharp') {
-    parsed = parseCSharpContent(content, filePath);
-  } else {
-    parsed = parseAngelscriptContent(content, filePath);
-  }
-
-  const types = [];
-  for (const cls of parsed.classes || []) types.push({ name: cls.name, kind: cls.kind || 'class', parent: cls.parent, line: cls.line });
-  for (const s of parsed.structs || []) types.push({ name: s.name, kind: 'struct', parent: s.parent || null, line: s.line });
-  for (const e of parsed.enums || []) types.push({ name: e.name, kind: 'enum', parent: null, line: e.line });
-  if (language === 'angelscript') {
-    for (const ev of parsed.events || []) types.push({ name: ev.name, kind: 'event', parent: null, line: ev.line });
-    for (const d of parsed.delegates || []) types.push({ name: d.name, kind: 'delegate', parent: null, line: d.line });
-    for (const ns of parsed.namespaces || []) types.push({ name: ns.name, kind: 'namespace', parent: null, line: ns.line });
-  }
-  if (language === 'cpp' || language === 'csharp') {
-    for (const d of parsed.delegates || []) types.push({ name: d.name, kind: 'delegate', parent: null, line: d.line });
-  }
-
-  return {
-    path: filePath,
-    project: project.name,
-    module,
-    mtime,
-    language,
-    content,
-    relativePath,
-    types,
-    members: parsed.members || []
-  };
+  return buildSourceEntry({ path: filePath, project, language, relativePath, mtime, content });
 }
 
 function parseAsset(filePath, project) {
@@ -297,7 +247,7 @@ async function runFullScan(languages) {
 
   for (const project of config.projects) {
     if (!languages.includes(project.language)) continue;
-    const extensions = project.extensions || (project.language === 'cpp' ? ['.h', '.cpp'] : ['.as']);
+    const extensions = projectExtensions(project);
 
     for (const basePath of project.paths) {
       const collectStart = performance.now();
@@ -365,7 +315,7 @@ async function runFullScan(languages) {
 
 async function runReconcile(project) {
   const language = project.language;
-  const extensions = project.extensions || (language === 'cpp' ? ['.h', '.cpp'] : ['.as']);
+  const extensions = projectExtensions(project);
 
   for (const basePath of project.paths) {
     const endpoint = language === 'content'
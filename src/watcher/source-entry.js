@@ -0,0 +1,70 @@
+// Source file selection and parsing shared by everything that ingests source
+// files: the watcher, its scan worker and the service's per-file refresh. A
+// file is collected and parsed the same way whichever of them sends it.
+
+import { parseContent as parseAngelscriptContent } from '../parsers/angelscript-parser.js';
+import { parseCppContent } from '../parsers/cpp-parser.js';
+import { parseCSharpContent } from '../parsers/csharp-parser.js';
+
+/** Extensions a source project collects: its own list or the language default. */
+export function projectExtensions(project) {
+  return project.extensions || (project.language === 'cpp' ? ['.h', '.cpp'] : ['.as']);
+}
+
+/**
+ * Whether a file name passes a project's extension list and, if it has any,
+ * its includePatterns ("*.Build.cs" suffixes or exact names).
+ */
+export function matchesSourceFile(fileName, extensions, includePatterns) {
+  const basename = fileName.split(/[/\\]/).pop();
+  if (!extensions.some(ext => basename.endsWith(ext))) return false;
+  if (includePatterns?.length > 0) {
+    return includePatterns.some(pat => pat.startsWith('*') ? basename.endsWith(pat.slice(1)) : basename === pat);
+  }
+  return true;
+}
+
+export function deriveModule(relativePath, projectName) {
+  const parts = relativePath.replace(/\.(as|h|cpp|cs)$/, '').split('/');
+  parts.pop();
+  return [projectName, ...parts].join('.');
+}
+
+/**
+ * Build an /internal/ingest file entry from a source file's content, parsing
+ * its types and members for the project's language.
+ */
+export function buildSourceEntry({ path, project, language, relativePath, mtime, content }) {
+  const module = deriveModule(relativePath, project.name);
+  if (language === 'config') {
+    return { path, project: project.name, module, mtime, language, relativePath, content, types: [], members: [] };
+  }
+
+  let parsed;
+  if (language === 'cpp') {
+    parsed = parseCppContent(content, path);
+  } else if (language === 'csharp') {
+    parsed = parseCSharpContent(content, path);
+  } else {
+    parsed = parseAngelscriptContent(content, path);
+  }
+
+  const types = [];
+  for (const cls of parsed.classes || []) types.push({ name: cls.name, kind: cls.kind || 'class', parent: cls.parent, line: cls.line });
+  for (const s of parsed.structs || []) types.push({ name: s.name, kind: 'struct', parent: s.parent || null, line: s.line });
+  for (const e of parsed.enums || []) types.push({ name: e.name, kind: 'enum', parent: null, line: e.line });
+  if (language === 'angelscript') {
+    for (const ev of parsed.events || []) types.push({ name: ev.name, kind: 'event', parent: null, line: ev.line });
+    for (const d of parsed.delegates || []) types.push({ name: d.name, kind: 'delegate', parent: null, line: d.line });
+    for (const ns of parsed.namespaces || []) types.push({ name: ns.name, kind: 'namespace', parent: null, line: ns.line });
+  }
+  if (language === 'cpp' || language === 'csharp') {
+    for (const d of parsed.delegates || []) types.push({ name: d.name, kind: 'delegate', parent: null, line: d.line });
+  }
+
+  return {
+    path, project: project.name, module, mtime, language, content,
+    relativePath,
+    types, members: parsed.members || []
+  };
+}
@@ -17,12 +17,10 @@ import { readFile, stat } from 'fs/promises';
 import { readdirSync, statSync, readFileSync, existsSync } from 'fs';
 import { join, relative } from 'path';
 import { Worker } from 'worker_threads';
-import { parseContent as parseAngelscriptContent } from '../parsers/angelscript-parser.js';
-import { parseCppContent } from '../parsers/cpp-parser.js';
-import { parseCSharpContent } from '../parsers/csharp-parser.js';
 import { parseUAssetHeader } from '../parsers/uasset-parser.js';
 import { gzipSync } from 'zlib';
 import { compileExcludePatterns, shouldExcludePath } from './exclude-patterns.js';
+import { buildSourceEntry, deriveModule, matchesSourceFile, projectExtensions } from './source-entry.js';
 import { getConfigReloadAction, mergeScanTelemetrySnapshot, reconcileFinalTelemetry } from './scan-telemetry.js';
 
 // --- Config ---
@@ -231,21 +229,7 @@ function findBasePathForFile(filePath, project) {
 }
 
 function hasMatchingExtension(filePath, project) {
-  const extensions = project.extensions || (project.language === 'cpp' ? ['.h', '.cpp'] : ['.as']);
-  if (!extensions.some(ext => filePath.endsWith(ext))) return false;
-  if (project.includePatterns?.length > 0) {
-    const basename = filePath.split(/[/\\]/).pop();
-    return project.includePatterns.some(pat =>
-      pat.startsWith('*') ? basename.endsWith(pat.slice(1)) : basename === pat
-    );
-  }
-  return true;
-}
-
-function deriveModule(relativePath, projectName) {
-  const parts = relativePath.replace(/\.(as|h|cpp|cs)$/, '').split('/');
-  parts.pop();
-  return [projectName, ...parts].join('.');
+  return matchesSourceFile(filePath, projectExtensions(project), project.includePatterns);
 }
 
 function shouldExclude(path) {
@@ -263,10 +247,7 @@ function collectFiles(dirPath, projectName, extensions, language, includePattern
         if (shouldExclude(fullPath)) continue;
         scanDir(fullPath);
       } else if (entry.isFile()) {
-        if (!extensions.some(ext => entry.name.endsWith(ext))) continue;
-        if (includePatterns?.length > 0) {
-          if (!includePatterns.some(pat => pat.startsWith('*') ? entry.name.endsWith(pat.slice(1)) : entry.name === pat)) continue;
-        }
+        if (!matchesSourceFile(entry.name, extensions, includePatterns)) continue;
         if (shouldExclude(fullPath)) continue;
         try {
           const mtime = Math.floor(statSync(fullPath).mtimeMs);
@@ -347,41 +328,8 @@ async function readAndParseSource(filePath, project, language) {
   const fileStat = await stat(filePath);
   const mtime = Math.floor(fileStat.mtimeMs);
   const relativePath = relative(basePath, filePath).replace(/\\/g, '/');
-  const module = deriveModule(relativePath, project.name);
-
-  if (language === 'config') {
-    const content = await readFile(filePath, 'utf-8');
-    return { path: filePath, project: project.name, module, mtime, language, relativePath, content, types: [], members: [] };
-  }
-
   const content = await readFile(filePath, 'utf-8');
-  let parsed;
-  if (language === 'cpp') {
-    parsed = parseCppContent(content, filePath);
-  } else if (language === 'csharp') {
-    parsed = parseCSharpContent(content, filePath);
-  } else {
-    parsed = parseAngelscriptContent(content, filePath);
-  }
-
-  const types = [];
-  for (const cls of parsed.classes || []) types.push({ name: cls.name, kind: cls.kind || 'class', parent: cls.parent, line: cls.line });
-  for (const s of parsed.structs || []) types.push({ name: s.name, kind: 'struct', parent: s.parent || null, line: s.line });
-  for (const e of parsed.enums || []) types.push({ name: e.name, kind: 'enum', parent: null, line: e.line });
-  if (language === 'angelscript') {
-    for (const ev of parsed.events || []) types.push({ name: ev.name, kind: 'event', parent: null, line: ev.line });
-    for (const d of parsed.delegates || []) types.push({ name: d.name, kind: 'delegate', parent: null, line: d.line });
-    for (const ns of parsed.namespaces || []) types.push({ name: ns.name, kind: 'namespace', parent: null, line: ns.line });
-  }
-  if (language === 'cpp' || language === 'csharp') {
-    for (const d of parsed.delegates || []) types.push({ name: d.name, kind: 'delegate', parent: null, line: d.line });
-  }
-
-  return {
-    path: filePath, project: project.name, module, mtime, language, content,
-    relativePath,
-    types, members: parsed.members || []
-  };
+  return buildSourceEntry({ path: filePath, project, language, relativePath, mtime, content });
 }
 
 function parseAsset(filePath, project) {
@@ -447,7 +395,7 @@ async function waitForService() {
 
 async function reconcile(project) {
   const language = project.language;
-  const extensions = project.extensions || (language === 'cpp' ? ['.h', '.cpp'] : ['.as']);
+  const extensions = projectExtensions(project);
 
   for (const basePath of project.paths) {
     // Step 1: Get stored mtimes from service
@@ -555,7 +503,7 @@ async function fullScan(languages) {
 
   for (const project of config.projects) {
     if (!languages.includes(project.language)) continue;
-    const extensions = project.extensions || (project.language === 'cpp' ? ['.h', '.cpp'] : ['.as']);
+    const extensions = projectExtensions(project);
 
     for (const basePath of project.paths) {
       const collectStart = performance.now();
@@ -108,8 +108,13 @@ export function generateDockerComposeContent(wsConfig, rootDir) {
     lines.push(`    container_name: unreal-index-${name}`);
     lines.push(`    ports:`);
     lines.push(`      - "${ws.port}:3847"`);
+    if (ws.zoektPort) {
+      // Published for the hook proxy's direct Zoekt fallback
+      lines.push(`      - "${ws.zoektPort}:${wsConfig.shared?.zoekt?.webPort || 6070}"`);
+    }
     lines.push(`    volumes:`);
-    lines.push(`      - ${vol}-db:/data/db`);
+    // dataDir bind-mounts the database from the host instead of a named volume
+    lines.push(ws.dataDir ? `      - ${fwd(ws.dataDir)}:/data/db` : `      - ${vol}-db:/data/db`);
     lines.push(`      - ${vol}-mirror:/data/mirror`);
     lines.push(`      - ${vol}-zoekt:/data/zoekt-index`);
     lines.push(`      - ./workspaces.json:/app/workspaces.json:ro`);
@@ -124,8 +129,10 @@ export function generateDockerComposeContent(wsConfig, rootDir) {
   lines.push('volumes:');
   for (const [name, ws] of Object.entries(wsConfig.workspaces)) {
     const vol = ws.volumePrefix || name;
-    lines.push(`  ${vol}-db:`);
-    lines.push(`    name: unreal-index-${vol}-db`);
+    if (!ws.dataDir) {
+      lines.push(`  ${vol}-db:`);
+      lines.push(`    name: unreal-index-${vol}-db`);
+    }
     lines.push(`  ${vol}-mirror:`);
     lines.push(`    name: unreal-index-${vol}-mirror`);
     lines.push(`  ${vol}-zoekt:`);